@@ -1,11 +1,12 @@
 package cfn
 
 import (
+	"bytes"
 	"context"
 	"errors"
-	"fmt"
 	"net/url"
 	"strings"
+	"text/template"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -25,18 +26,20 @@ func New(cfg aws.Config) *Cfn {
 }
 
 // GetStack returns a cloudformation.Stack representing the named stack
-func (c *Cfn) GetStack(ctx context.Context, stackName string) (types.Stack, error) {
+func (c *Cfn) GetStack(ctx context.Context, stackName string, opts ...RequestOptFunc) (types.Stack, error) {
+	ro := makeRequestOpts(opts)
+
 	// Get the stack properties
 	res, err := c.client.DescribeStacks(ctx, &cloudformation.DescribeStacksInput{
 		StackName: &stackName,
-	})
+	}, ro.ClientOptions...)
 	var ve *smithy.GenericAPIError
-	if err != nil && !errors.As(err, &ve) {
-		return types.Stack{}, err
-	}
-	if ve != nil && ve.Code == "ValidationError" {
+	if err != nil && errors.As(err, &ve) && ve.Code == "ValidationError" {
 		return types.Stack{}, ErrStackNotExist
 	}
+	if err != nil {
+		return types.Stack{}, err
+	}
 
 	return res.Stacks[0], nil
 }
@@ -44,11 +47,13 @@ func (c *Cfn) GetStack(ctx context.Context, stackName string) (types.Stack, erro
 var ErrStackNotExist = errors.New("stack does not exist")
 
 // GetStackResources returns a list of the resources in the named stack
-func (c *Cfn) GetStackResources(ctx context.Context, stackName string) ([]types.StackResource, error) {
+func (c *Cfn) GetStackResources(ctx context.Context, stackName string, opts ...RequestOptFunc) ([]types.StackResource, error) {
+	ro := makeRequestOpts(opts)
+
 	// Get the stack resources
 	res, err := c.client.DescribeStackResources(ctx, &cloudformation.DescribeStackResourcesInput{
 		StackName: &stackName,
-	})
+	}, ro.ClientOptions...)
 	if err != nil {
 		return nil, err
 	}
@@ -56,8 +61,59 @@ func (c *Cfn) GetStackResources(ctx context.Context, stackName string) ([]types.
 	return res.StackResources, nil
 }
 
+// GetStackEvents returns the most recent events of the named stack, newest first.
+// At most limit events are returned.
+func (c *Cfn) GetStackEvents(ctx context.Context, stackName string, limit int, opts ...RequestOptFunc) ([]types.StackEvent, error) {
+	ro := makeRequestOpts(opts)
+
+	out := make([]types.StackEvent, 0)
+
+	p := cloudformation.NewDescribeStackEventsPaginator(c.client, &cloudformation.DescribeStackEventsInput{
+		StackName: &stackName,
+	})
+	for p.HasMorePages() && len(out) < limit {
+		res, err := p.NextPage(ctx, ro.ClientOptions...)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, res.StackEvents...)
+	}
+
+	if len(out) > limit {
+		out = out[:limit]
+	}
+
+	return out, nil
+}
+
+// GetTemplate returns the template body of the named stack.
+// If changeSetName is set, the template associated with the changeset is returned instead.
+func (c *Cfn) GetTemplate(ctx context.Context, stackName, changeSetName string, opts ...RequestOptFunc) (string, error) {
+	ro := makeRequestOpts(opts)
+
+	input := &cloudformation.GetTemplateInput{}
+
+	if stackName != "" {
+		input.StackName = aws.String(stackName)
+	}
+
+	// Change set name is optional
+	if changeSetName != "" {
+		input.ChangeSetName = aws.String(changeSetName)
+	}
+
+	res, err := c.client.GetTemplate(ctx, input, ro.ClientOptions...)
+	if err != nil {
+		return "", err
+	}
+
+	return ptr.ToString(res.TemplateBody), nil
+}
+
 // GetChangeSet returns the named changeset
-func (c *Cfn) GetChangeSet(ctx context.Context, stackName, changeSetName string) (*cloudformation.DescribeChangeSetOutput, error) {
+func (c *Cfn) GetChangeSet(ctx context.Context, stackName, changeSetName string, opts ...RequestOptFunc) (*cloudformation.DescribeChangeSetOutput, error) {
+	ro := makeRequestOpts(opts)
+
 	input := &cloudformation.DescribeChangeSetInput{
 		ChangeSetName: aws.String(changeSetName),
 	}
@@ -67,16 +123,47 @@ func (c *Cfn) GetChangeSet(ctx context.Context, stackName, changeSetName string)
 		input.StackName = aws.String(stackName)
 	}
 
-	return c.client.DescribeChangeSet(ctx, input)
+	return c.client.DescribeChangeSet(ctx, input, ro.ClientOptions...)
+}
+
+// ChangeSetNameData is passed to the change set name template
+type ChangeSetNameData struct {
+	StackName string
+	Timestamp int64
+	Time      time.Time
+}
+
+// ChangeSetName renders a change set name from a template
+func ChangeSetName(tmpl string, stackName string, now time.Time) (string, error) {
+	t, err := template.New("changeset").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	err = t.Execute(&buf, ChangeSetNameData{
+		StackName: stackName,
+		Timestamp: now.Unix(),
+		Time:      now,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
 }
 
 // CreateChangeSet creates a changeset
 // template can be either a URL or a template body
-func (c *Cfn) CreateChangeSet(ctx context.Context, template string, params []types.Parameter, tags map[string]string, stackName string, roleArn string) (string, error) {
+//
+// If the changeset fails to create, or contains no changes,
+// it is deleted before the error is returned.
+func (c *Cfn) CreateChangeSet(ctx context.Context, template string, params []types.Parameter, tags map[string]string, stackName string, roleArn string, opts ...RequestOptFunc) (string, error) {
+	ro := makeRequestOpts(opts)
 
 	changeSetType := "CREATE"
 
-	existingStack, err := c.GetStack(ctx, stackName)
+	existingStack, err := c.GetStack(ctx, stackName, opts...)
 	if err != nil && err != ErrStackNotExist {
 		return "", err
 	}
@@ -95,7 +182,10 @@ func (c *Cfn) CreateChangeSet(ctx context.Context, template string, params []typ
 		templateBody = &template
 	}
 
-	changeSetName := stackName + "-" + fmt.Sprint(time.Now().Unix())
+	changeSetName, err := ChangeSetName(ro.ChangeSetNameTemplate, stackName, time.Now())
+	if err != nil {
+		return "", err
+	}
 
 	input := &cloudformation.CreateChangeSetInput{
 		ChangeSetType:       types.ChangeSetType(changeSetType),
@@ -116,7 +206,25 @@ func (c *Cfn) CreateChangeSet(ctx context.Context, template string, params []typ
 		input.RoleARN = ptr.String(roleArn)
 	}
 
-	_, err = c.client.CreateChangeSet(ctx, input)
+	if ro.ChangeSetDescription != "" {
+		input.Description = ptr.String(ro.ChangeSetDescription)
+	}
+
+	if ro.ClientRequestToken != "" {
+		input.ClientToken = ptr.String(ro.ClientRequestToken)
+	}
+
+	if ro.RollbackConfiguration != nil {
+		input.RollbackConfiguration = ro.RollbackConfiguration
+	}
+
+	// CloudFormation only honours DisableRollback on ExecuteChangeSet for existing stacks.
+	// New stacks need it set on the change set instead, and then it must not be passed to ExecuteChangeSet.
+	if ro.DisableRollback && input.ChangeSetType == types.ChangeSetTypeCreate {
+		input.OnStackFailure = types.OnStackFailureDoNothing
+	}
+
+	_, err = c.client.CreateChangeSet(ctx, input, ro.ClientOptions...)
 	if err != nil {
 		return changeSetName, err
 	}
@@ -125,7 +233,7 @@ func (c *Cfn) CreateChangeSet(ctx context.Context, template string, params []typ
 		res, err := c.client.DescribeChangeSet(ctx, &cloudformation.DescribeChangeSetInput{
 			ChangeSetName: &changeSetName,
 			StackName:     &stackName,
-		})
+		}, ro.ClientOptions...)
 		if err != nil {
 			return changeSetName, err
 		}
@@ -133,6 +241,10 @@ func (c *Cfn) CreateChangeSet(ctx context.Context, template string, params []typ
 		status := string(res.Status)
 
 		if status == "FAILED" {
+			// Failed and empty changesets are never executable so don't leave them lying around.
+			// We ignore errors here as the changeset can be pruned later with ListChangeSets.
+			_ = c.DeleteChangeSet(ctx, stackName, changeSetName, opts...)
+
 			return changeSetName, errors.New(ptr.ToString(res.StatusReason))
 		}
 
@@ -140,26 +252,145 @@ func (c *Cfn) CreateChangeSet(ctx context.Context, template string, params []typ
 			break
 		}
 
-		time.Sleep(time.Second * 2)
+		err = sleep(ctx, time.Second*2)
+		if err != nil {
+			return changeSetName, err
+		}
 	}
 
 	return changeSetName, nil
 }
 
+// TemplateError is returned by ValidateTemplate when CloudFormation rejects a template
+type TemplateError struct {
+	Message string
+}
+
+func (e *TemplateError) Error() string {
+	return e.Message
+}
+
+// ValidateTemplate checks a template with CloudFormation.
+// template can be either a URL or a template body.
+// If the template is invalid, a *TemplateError is returned.
+// ErrAccessDenied is returned if the caller isn't allowed to validate templates.
+func (c *Cfn) ValidateTemplate(ctx context.Context, template string, opts ...RequestOptFunc) error {
+	ro := makeRequestOpts(opts)
+
+	input := &cloudformation.ValidateTemplateInput{}
+
+	u, err := url.Parse(template)
+	if err == nil {
+		input.TemplateURL = aws.String(u.String())
+	} else {
+		input.TemplateBody = &template
+	}
+
+	_, err = c.client.ValidateTemplate(ctx, input, ro.ClientOptions...)
+	var ve *smithy.GenericAPIError
+	if err != nil && errors.As(err, &ve) && ve.Code == "ValidationError" {
+		return &TemplateError{Message: ve.Message}
+	}
+	if err != nil && errors.As(err, &ve) && ve.Code == "AccessDenied" {
+		return ErrAccessDenied
+	}
+
+	return err
+}
+
+// ErrAccessDenied is returned by ValidateTemplate when the caller
+// doesn't have the cloudformation:ValidateTemplate permission
+var ErrAccessDenied = errors.New("access denied")
+
+// ListChangeSets returns a summary of every changeset belonging to the named stack
+func (c *Cfn) ListChangeSets(ctx context.Context, stackName string, opts ...RequestOptFunc) ([]types.ChangeSetSummary, error) {
+	ro := makeRequestOpts(opts)
+
+	out := make([]types.ChangeSetSummary, 0)
+
+	p := cloudformation.NewListChangeSetsPaginator(c.client, &cloudformation.ListChangeSetsInput{
+		StackName: &stackName,
+	})
+	for p.HasMorePages() {
+		res, err := p.NextPage(ctx, ro.ClientOptions...)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, res.Summaries...)
+	}
+
+	return out, nil
+}
+
+// DeleteChangeSet deletes the named changeset
+func (c *Cfn) DeleteChangeSet(ctx context.Context, stackName, changeSetName string, opts ...RequestOptFunc) error {
+	ro := makeRequestOpts(opts)
+
+	input := &cloudformation.DeleteChangeSetInput{
+		ChangeSetName: aws.String(changeSetName),
+	}
+
+	// Stack name is optional
+	if stackName != "" {
+		input.StackName = aws.String(stackName)
+	}
+
+	_, err := c.client.DeleteChangeSet(ctx, input, ro.ClientOptions...)
+	return err
+}
+
 // ExecuteChangeSet executes the named changeset
-func (c *Cfn) ExecuteChangeSet(ctx context.Context, stackName, changeSetName string) error {
-	_, err := c.client.ExecuteChangeSet(ctx, &cloudformation.ExecuteChangeSetInput{
+func (c *Cfn) ExecuteChangeSet(ctx context.Context, stackName, changeSetName string, opts ...RequestOptFunc) error {
+	ro := makeRequestOpts(opts)
+
+	input := &cloudformation.ExecuteChangeSetInput{
 		ChangeSetName: &changeSetName,
 		StackName:     &stackName,
-	})
+	}
+
+	if ro.ClientRequestToken != "" {
+		input.ClientRequestToken = ptr.String(ro.ClientRequestToken)
+	}
+
+	if ro.DisableRollback {
+		input.DisableRollback = ptr.Bool(true)
+	}
+
+	_, err := c.client.ExecuteChangeSet(ctx, input, ro.ClientOptions...)
+
+	return err
+}
+
+// RollbackStack rolls back a stack which failed to be created or updated
+// with rollback disabled, returning it to its last stable state
+func (c *Cfn) RollbackStack(ctx context.Context, stackName string, roleArn string, opts ...RequestOptFunc) error {
+	ro := makeRequestOpts(opts)
+
+	input := &cloudformation.RollbackStackInput{
+		StackName: &stackName,
+	}
+
+	// roleArn is optional
+	if roleArn != "" {
+		input.RoleARN = ptr.String(roleArn)
+	}
+
+	if ro.ClientRequestToken != "" {
+		input.ClientRequestToken = ptr.String(ro.ClientRequestToken)
+	}
+
+	_, err := c.client.RollbackStack(ctx, input, ro.ClientOptions...)
 
 	return err
 }
 
 // DeleteStack deletes a stack
-func (c *Cfn) DeleteStack(stackName string, roleArn string) (*cloudformation.DeleteStackOutput, error) {
+func (c *Cfn) DeleteStack(ctx context.Context, stackName string, roleArn string, opts ...RequestOptFunc) (*cloudformation.DeleteStackOutput, error) {
+	ro := makeRequestOpts(opts)
+
 	input := &cloudformation.DeleteStackInput{
-		StackName: &stackName,
+		StackName:       &stackName,
+		RetainResources: ro.RetainResources,
 	}
 
 	// roleArn is optional
@@ -167,7 +398,63 @@ func (c *Cfn) DeleteStack(stackName string, roleArn string) (*cloudformation.Del
 		input.RoleARN = ptr.String(roleArn)
 	}
 
-	return c.client.DeleteStack(context.Background(), input)
+	if ro.ClientRequestToken != "" {
+		input.ClientRequestToken = ptr.String(ro.ClientRequestToken)
+	}
+
+	return c.client.DeleteStack(ctx, input, ro.ClientOptions...)
+}
+
+// GetStackPolicy returns the stack policy of the named stack.
+// An empty string is returned if the stack has no policy.
+func (c *Cfn) GetStackPolicy(ctx context.Context, stackName string, opts ...RequestOptFunc) (string, error) {
+	ro := makeRequestOpts(opts)
+
+	res, err := c.client.GetStackPolicy(ctx, &cloudformation.GetStackPolicyInput{
+		StackName: &stackName,
+	}, ro.ClientOptions...)
+	if err != nil {
+		return "", err
+	}
+
+	return ptr.ToString(res.StackPolicyBody), nil
+}
+
+// SetStackPolicy sets the stack policy of the named stack
+func (c *Cfn) SetStackPolicy(ctx context.Context, stackName string, policyBody string, opts ...RequestOptFunc) error {
+	ro := makeRequestOpts(opts)
+
+	_, err := c.client.SetStackPolicy(ctx, &cloudformation.SetStackPolicyInput{
+		StackName:       &stackName,
+		StackPolicyBody: &policyBody,
+	}, ro.ClientOptions...)
+
+	return err
+}
+
+// UpdateTerminationProtection enables or disables termination protection on the named stack
+func (c *Cfn) UpdateTerminationProtection(ctx context.Context, stackName string, enabled bool, opts ...RequestOptFunc) error {
+	ro := makeRequestOpts(opts)
+
+	_, err := c.client.UpdateTerminationProtection(ctx, &cloudformation.UpdateTerminationProtectionInput{
+		StackName:                   &stackName,
+		EnableTerminationProtection: &enabled,
+	}, ro.ClientOptions...)
+
+	return err
+}
+
+// sleep waits for d, returning early with an error if ctx is cancelled
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
 }
 
 func makeTags(tags map[string]string) []types.Tag {
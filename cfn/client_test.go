@@ -0,0 +1,28 @@
+package cfn
+
+import (
+	"testing"
+	"time"
+)
+
+func TestChangeSetName(t *testing.T) {
+	now := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	for tmpl, expected := range map[string]string{
+		DefaultChangeSetNameTemplate:                   "my-stack-1677672000",
+		"{{.StackName}}-{{.Time.Format \"20060102\"}}": "my-stack-20230301",
+		"deploy-{{.Timestamp}}":                        "deploy-1677672000",
+	} {
+		actual, err := ChangeSetName(tmpl, "my-stack", now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual != expected {
+			t.Errorf("Got '%s'. Want: '%s'.", actual, expected)
+		}
+	}
+
+	if _, err := ChangeSetName("{{.Missing}}", "my-stack", now); err == nil {
+		t.Error("expected an error for an unknown field")
+	}
+}
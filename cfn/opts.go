@@ -0,0 +1,108 @@
+package cfn
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+)
+
+// DefaultChangeSetNameTemplate is used to name change sets
+// when RequestOpts.ChangeSetNameTemplate is not set.
+const DefaultChangeSetNameTemplate = "{{.StackName}}-{{.Timestamp}}"
+
+// RequestOpts holds optional settings for requests made by Cfn.
+// Settings which don't apply to a request are ignored.
+type RequestOpts struct {
+	// ChangeSetNameTemplate is a text/template used to name
+	// change sets. It is executed with a ChangeSetNameData.
+	ChangeSetNameTemplate string
+	// ChangeSetDescription is an optional description
+	// attached to created change sets
+	ChangeSetDescription string
+	// RetainResources are the logical IDs of resources to keep
+	// when deleting a stack which is in the DELETE_FAILED state
+	RetainResources []string
+	// ClientRequestToken identifies a request so that CloudFormation
+	// can tell retries apart from new requests. It is used by
+	// CreateChangeSet, ExecuteChangeSet and DeleteStack.
+	ClientRequestToken string
+	// RollbackConfiguration sets the rollback triggers and monitoring period
+	// of created change sets. If it is nil, the stack's existing configuration is kept.
+	RollbackConfiguration *types.RollbackConfiguration
+	// DisableRollback leaves resources in place when an executed change set fails,
+	// rather than rolling the stack back. Change sets which create a stack are
+	// created with OnStackFailure set to DO_NOTHING; for other change sets it is
+	// passed to ExecuteChangeSet.
+	DisableRollback bool
+	// ClientOptions are applied to the underlying CloudFormation API call
+	ClientOptions []func(*cloudformation.Options)
+}
+
+type RequestOptFunc func(*RequestOpts)
+
+// WithChangeSetNameTemplate sets the template used to name change sets.
+func WithChangeSetNameTemplate(tmpl string) RequestOptFunc {
+	return func(ro *RequestOpts) {
+		ro.ChangeSetNameTemplate = tmpl
+	}
+}
+
+// WithChangeSetDescription sets the description of created change sets.
+func WithChangeSetDescription(description string) RequestOptFunc {
+	return func(ro *RequestOpts) {
+		ro.ChangeSetDescription = description
+	}
+}
+
+// WithRetainResources sets the resources to retain when deleting a stack.
+func WithRetainResources(logicalIDs ...string) RequestOptFunc {
+	return func(ro *RequestOpts) {
+		ro.RetainResources = logicalIDs
+	}
+}
+
+// WithClientRequestToken sets the idempotency token of the request.
+func WithClientRequestToken(token string) RequestOptFunc {
+	return func(ro *RequestOpts) {
+		ro.ClientRequestToken = token
+	}
+}
+
+// WithRollbackConfiguration sets the rollback triggers and monitoring period of created change sets.
+func WithRollbackConfiguration(rc *types.RollbackConfiguration) RequestOptFunc {
+	return func(ro *RequestOpts) {
+		ro.RollbackConfiguration = rc
+	}
+}
+
+// WithDisableRollback stops executed change sets from being rolled back if they fail.
+// Pass it to CreateChangeSet for new stacks, and to ExecuteChangeSet for existing ones.
+func WithDisableRollback(disable bool) RequestOptFunc {
+	return func(ro *RequestOpts) {
+		ro.DisableRollback = disable
+	}
+}
+
+// WithRetryer overrides the retryer used by the request.
+func WithRetryer(retryer aws.Retryer) RequestOptFunc {
+	return WithClientOptions(func(o *cloudformation.Options) {
+		o.Retryer = retryer
+	})
+}
+
+// WithClientOptions adds options which are applied to the underlying CloudFormation API call.
+func WithClientOptions(opts ...func(*cloudformation.Options)) RequestOptFunc {
+	return func(ro *RequestOpts) {
+		ro.ClientOptions = append(ro.ClientOptions, opts...)
+	}
+}
+
+func makeRequestOpts(opts []RequestOptFunc) RequestOpts {
+	ro := RequestOpts{
+		ChangeSetNameTemplate: DefaultChangeSetNameTemplate,
+	}
+	for _, o := range opts {
+		o(&ro)
+	}
+	return ro
+}
@@ -2,13 +2,18 @@ package console
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/gookit/color"
 )
 
+// wrap returns a helper which renders its input in style c. Helpers only add colour codes
+// if NoColour isn't set and Default has colour enabled, as they don't know which Terminal
+// their output is written to. WithColour and SetColour on other terminals don't affect them;
+// those terminals remove colour codes from what is written to them instead.
 func wrap(c color.Style) func(...interface{}) string {
 	return func(in ...interface{}) string {
-		if NoColour || !IsTTY {
+		if NoColour || !Default.Colour() {
 			return fmt.Sprint(in...)
 		}
 
@@ -16,11 +21,12 @@ func wrap(c color.Style) func(...interface{}) string {
 	}
 }
 
-// Sprint wraps color.Sprint with logic to ignore colours if the console does not support colour
+// Sprint wraps color.Sprint with logic to ignore colours if the console does not support colour.
+// Like the colour helpers, it follows Default.
 func Sprint(in ...interface{}) string {
 	out := color.Sprint(in...)
 
-	if NoColour || !IsTTY {
+	if NoColour || !Default.Colour() {
 		out = color.ClearCode(out)
 	}
 
@@ -53,3 +59,56 @@ var Bold = wrap(color.New(color.Bold))
 
 // Plain returns the input as a string of normal-coloured text if the console supports colours
 var Plain = wrap(color.New(color.Normal))
+
+var styleNames = map[string]color.Color{
+	"plain":         color.Normal,
+	"black":         color.Black,
+	"red":           color.Red,
+	"green":         color.Green,
+	"yellow":        color.Yellow,
+	"blue":          color.Blue,
+	"magenta":       color.Magenta,
+	"cyan":          color.Cyan,
+	"white":         color.White,
+	"grey":          color.Gray,
+	"light-red":     color.LightRed,
+	"light-green":   color.LightGreen,
+	"light-yellow":  color.LightYellow,
+	"light-blue":    color.LightBlue,
+	"light-magenta": color.LightMagenta,
+	"light-cyan":    color.LightCyan,
+	"light-white":   color.LightWhite,
+	"bg-red":        color.BgRed,
+	"bg-green":      color.BgGreen,
+	"bg-yellow":     color.BgYellow,
+	"bg-blue":       color.BgBlue,
+	"bg-magenta":    color.BgMagenta,
+	"bg-cyan":       color.BgCyan,
+	"bold":          color.OpBold,
+	"underline":     color.OpUnderscore,
+	"reverse":       color.OpReverse,
+}
+
+// NewStyle returns a colour function like Red or Green from a space separated
+// list of colour names, such as "bold light-red". An empty spec returns Plain.
+//
+// Valid names are plain, black, red, green, yellow, blue, magenta, cyan, white and grey,
+// the light- variants of each, bg-red, bg-green, bg-yellow, bg-blue, bg-magenta, bg-cyan,
+// bold, underline and reverse.
+func NewStyle(spec string) (func(...interface{}) string, error) {
+	style := make(color.Style, 0)
+
+	for _, name := range strings.Fields(spec) {
+		c, ok := styleNames[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown colour '%s'", name)
+		}
+		style = append(style, c)
+	}
+
+	if len(style) == 0 {
+		return Plain, nil
+	}
+
+	return wrap(style), nil
+}
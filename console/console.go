@@ -4,16 +4,16 @@ package console
 import (
 	"errors"
 	"fmt"
-	"math"
 	"os"
 	"strings"
 
 	"github.com/chzyer/readline"
-	"github.com/gookit/color"
-	"github.com/nathan-fiscaletti/consolesize-go"
-	"golang.org/x/term"
 )
 
+// Default is the Terminal used by the package level functions. It writes to stdout.
+// The colour helpers such as Red and Green follow its colour setting.
+var Default *Terminal
+
 // IsTTY will be true if stdout is connected to a true terminal
 var IsTTY bool
 
@@ -24,64 +24,35 @@ var isANSI bool
 var NoColour = false
 
 func init() {
-	IsTTY = term.IsTerminal(int(os.Stdout.Fd()))
-	isANSI = true
+	SetDefault(NewTerminal(os.Stdout))
+}
+
+// SetDefault replaces the Terminal used by the package level functions,
+// for example to send all output to stderr
+func SetDefault(t *Terminal) {
+	Default = t
+	IsTTY = t.isTTY
+	isANSI = t.isANSI
 }
 
 // Size returns the width and height of the console in characters
 func Size() (int, int) {
-	return consolesize.GetConsoleSize()
+	return Default.Size()
 }
 
 // CountLines returns the number of lines that would be taken up by the given string
 func CountLines(input string) int {
-	input = color.ClearCode(input)
-
-	if input == "" {
-		return 0
-	}
-
-	w, _ := Size()
-
-	if w == 0 {
-		return 0
-	}
-
-	count := 0
-	for _, line := range strings.Split(input, "\n") {
-		d := int(math.Ceil(float64(len([]rune(line))) / float64(w)))
-		if d == 0 {
-			d = 1
-		}
-		count += d
-	}
-
-	return count
+	return Default.CountLines(input)
 }
 
 // ClearLine removes all text from the current line and puts the cursor on the left
 func ClearLine() {
-	if IsTTY && isANSI {
-		fmt.Print("\033[G\033[K")
-	} else {
-		fmt.Println()
-	}
+	Default.ClearLine()
 }
 
 // ClearLines removes all text from the previous n lines (starting with the current line) and puts the cursor on the left
 func ClearLines(n int) {
-	if !IsTTY {
-		return
-	}
-
-	for i := 0; i < n; i++ {
-		ClearLine()
-		if i < n-1 {
-			if IsTTY && isANSI {
-				fmt.Print("\033[F")
-			}
-		}
-	}
+	Default.ClearLines(n)
 }
 
 // Ask prints the supplied prompt and then waits for user input which is returned as a string.
@@ -122,3 +93,49 @@ func Confirm(defaultYes bool, prompt string) bool {
 
 	return false
 }
+
+// Truncate shortens s so that it takes up no more than width columns,
+// preserving any colour codes. Colours are reset if the string is cut short.
+func Truncate(s string, width int) string {
+	if width <= 0 {
+		return s
+	}
+
+	out := strings.Builder{}
+	visible := 0
+	coloured := false
+	runes := []rune(s)
+
+	for i := 0; i < len(runes); i++ {
+		// Copy escape sequences without counting them
+		if runes[i] == '\033' {
+			j := i + 1
+			if j < len(runes) && runes[j] == '[' {
+				j++
+				for j < len(runes) && (runes[j] < '@' || runes[j] > '~') {
+					j++
+				}
+			}
+			if j >= len(runes) {
+				j = len(runes) - 1
+			}
+			out.WriteString(string(runes[i : j+1]))
+			coloured = true
+			i = j
+			continue
+		}
+
+		w := RuneWidth(runes[i])
+		if visible+w > width {
+			if coloured {
+				out.WriteString("\033[0m")
+			}
+			break
+		}
+
+		out.WriteRune(runes[i])
+		visible += w
+	}
+
+	return out.String()
+}
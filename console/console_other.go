@@ -0,0 +1,8 @@
+//go:build !windows
+
+package console
+
+// ansiSupported returns true as all non-Windows terminals support ANSI escape codes
+func ansiSupported(fd uintptr) bool {
+	return true
+}
@@ -4,19 +4,11 @@
 package console
 
 import (
-	"os"
-
 	"golang.org/x/sys/windows"
-	"golang.org/x/term"
 )
 
-func init() {
-	var fd = os.Stdout.Fd()
-	IsTTY = term.IsTerminal(int(fd))
-	isANSI = isANSISupport(fd)
-}
-
-func isANSISupport(fd uintptr) bool {
+// ansiSupported returns true if the console has virtual terminal processing enabled
+func ansiSupported(fd uintptr) bool {
 	var consoleHandle = windows.Handle(fd)
 	var consoleMode uint32
 	err := windows.GetConsoleMode(consoleHandle, &consoleMode)
@@ -0,0 +1,13 @@
+//go:build !(aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris)
+
+package console
+
+import (
+	"io"
+	"os"
+)
+
+// openKeyInput returns stdin, which doesn't support read deadlines on this platform.
+func openKeyInput() io.Reader {
+	return os.Stdin
+}
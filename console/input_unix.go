@@ -0,0 +1,73 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+
+package console
+
+import (
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// pollInterval is how often a keyInput checks its deadline while waiting for input
+const pollInterval = 50 * time.Millisecond
+
+// keyInput reads from a file descriptor, waiting for input with poll so that reads
+// can be interrupted with a deadline. Unlike a non-blocking file, this leaves the
+// descriptor's flags alone, as they may be shared with stdout.
+type keyInput struct {
+	fd int
+
+	mu       sync.Mutex
+	deadline time.Time
+}
+
+// openKeyInput returns a reader for stdin which supports read deadlines
+func openKeyInput() io.Reader {
+	return &keyInput{fd: int(os.Stdin.Fd())}
+}
+
+func (k *keyInput) SetReadDeadline(t time.Time) error {
+	k.mu.Lock()
+	k.deadline = t
+	k.mu.Unlock()
+	return nil
+}
+
+func (k *keyInput) Read(p []byte) (int, error) {
+	for {
+		k.mu.Lock()
+		deadline := k.deadline
+		k.mu.Unlock()
+
+		if !deadline.IsZero() && !time.Now().Before(deadline) {
+			return 0, os.ErrDeadlineExceeded
+		}
+
+		fds := []unix.PollFd{{Fd: int32(k.fd), Events: unix.POLLIN}}
+		n, err := unix.Poll(fds, int(pollInterval/time.Millisecond))
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		if n == 0 {
+			continue
+		}
+
+		n, err = unix.Read(k.fd, p)
+		if err == unix.EINTR || err == unix.EAGAIN {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		if n == 0 {
+			return 0, io.EOF
+		}
+		return n, nil
+	}
+}
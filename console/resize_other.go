@@ -0,0 +1,9 @@
+//go:build !(aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris)
+
+package console
+
+// trackResize does nothing on platforms without SIGWINCH.
+// The size of the terminal is queried each time it is needed instead.
+func (t *Terminal) trackResize() bool {
+	return false
+}
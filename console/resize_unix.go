@@ -0,0 +1,33 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+
+package console
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// trackResize caches the size of the terminal and refreshes it whenever
+// the process receives SIGWINCH, until Close is called.
+// It is called with t.mu held and returns true once tracking has started.
+func (t *Terminal) trackResize() bool {
+	w, h := t.querySize()
+	t.size = [2]int{w, h}
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGWINCH)
+
+	go func() {
+		for range ch {
+			t.refreshSize()
+		}
+	}()
+
+	t.stopResize = func() {
+		signal.Stop(ch)
+		close(ch)
+	}
+
+	return true
+}
@@ -0,0 +1,154 @@
+package console
+
+import (
+	"bufio"
+	"io"
+	"sync"
+	"time"
+)
+
+// Key is a key press read by ReadKeys
+type Key string
+
+// Keys which are recognised by ReadKeys. Other keys are sent as the character typed.
+const (
+	KeyUp       Key = "up"
+	KeyDown     Key = "down"
+	KeyLeft     Key = "left"
+	KeyRight    Key = "right"
+	KeyPageUp   Key = "pgup"
+	KeyPageDown Key = "pgdown"
+	KeyHome     Key = "home"
+	KeyEnd      Key = "end"
+	KeyTab      Key = "tab"
+	KeyEnter    Key = "enter"
+	KeyEscape   Key = "esc"
+	KeyCtrlC    Key = "ctrl-c"
+)
+
+// IsInteractive returns true if both stdin and stdout are connected to a terminal
+func IsInteractive() bool {
+	return Default.IsInteractive()
+}
+
+// EnterFullScreen switches stdout to the terminal's alternate screen. See Terminal.EnterFullScreen.
+func EnterFullScreen() (func(), error) {
+	return Default.EnterFullScreen()
+}
+
+// DrawScreen redraws the whole screen with the given lines, starting from the top left.
+// Lines are overwritten in place rather than cleared first so that the screen doesn't flicker.
+func DrawScreen(w io.Writer, lines []string) {
+	buf := bufio.NewWriter(w)
+
+	buf.WriteString("\033[H")
+	for i, line := range lines {
+		buf.WriteString(line)
+		buf.WriteString("\033[K")
+		if i < len(lines)-1 {
+			buf.WriteString("\r\n")
+		}
+	}
+	buf.WriteString("\033[J")
+
+	buf.Flush()
+}
+
+// ReadKeys reads key presses from r and sends them on the returned channel until the
+// returned stop function is called or r returns an error, when the channel is closed.
+// If r supports read deadlines, stop interrupts the read in progress and waits for it to
+// return, so that no more input is consumed after stop returns. Otherwise the next
+// key press is still read from r, but it is discarded.
+func ReadKeys(r io.Reader) (<-chan Key, func()) {
+	keys := make(chan Key)
+	done := make(chan struct{})
+	exited := make(chan struct{})
+
+	go func() {
+		defer close(exited)
+		defer close(keys)
+
+		buf := make([]byte, 16)
+		for {
+			n, err := r.Read(buf)
+			if err != nil {
+				return
+			}
+
+			for _, key := range parseKeys(buf[:n]) {
+				select {
+				case keys <- key:
+				case <-done:
+					return
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			close(done)
+
+			d, ok := r.(interface{ SetReadDeadline(time.Time) error })
+			if ok && d.SetReadDeadline(time.Now()) == nil {
+				<-exited
+			}
+		})
+	}
+
+	return keys, stop
+}
+
+// ReadStdinKeys reads key presses from stdin. See ReadKeys.
+// Where the platform allows it, stop leaves later key presses for whatever
+// reads stdin next, such as a prompt.
+func ReadStdinKeys() (<-chan Key, func()) {
+	return ReadKeys(openKeyInput())
+}
+
+var escapeKeys = map[string]Key{
+	"[A":  KeyUp,
+	"[B":  KeyDown,
+	"[C":  KeyRight,
+	"[D":  KeyLeft,
+	"[5~": KeyPageUp,
+	"[6~": KeyPageDown,
+	"[H":  KeyHome,
+	"[F":  KeyEnd,
+	"[1~": KeyHome,
+	"[4~": KeyEnd,
+}
+
+// parseKeys splits raw terminal input into key presses
+func parseKeys(in []byte) []Key {
+	keys := make([]Key, 0)
+
+	for i := 0; i < len(in); i++ {
+		switch in[i] {
+		case 3:
+			keys = append(keys, KeyCtrlC)
+		case '\t':
+			keys = append(keys, KeyTab)
+		case '\r', '\n':
+			keys = append(keys, KeyEnter)
+		case 27:
+			found := false
+			for seq, key := range escapeKeys {
+				if i+len(seq) < len(in) && string(in[i+1:i+1+len(seq)]) == seq {
+					keys = append(keys, key)
+					i += len(seq)
+					found = true
+					break
+				}
+			}
+			if !found {
+				keys = append(keys, KeyEscape)
+			}
+		default:
+			keys = append(keys, Key(string(in[i])))
+		}
+	}
+
+	return keys
+}
@@ -0,0 +1,65 @@
+package console
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseKeys(t *testing.T) {
+	actual := parseKeys([]byte("q\t\033[A\033[6~\033j"))
+	expected := []Key{"q", KeyTab, KeyUp, KeyPageDown, KeyEscape, "j"}
+
+	if d := cmp.Diff(expected, actual); d != "" {
+		t.Error(d)
+	}
+}
+
+func TestReadKeysStop(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	keys, stop := ReadKeys(r)
+
+	w.Write([]byte("j"))
+	if key := <-keys; key != "j" {
+		t.Fatalf("expected j, got %q", key)
+	}
+
+	stop()
+	if _, ok := <-keys; ok {
+		t.Fatal("expected keys to be closed after stop")
+	}
+
+	// Input after stop is left for the next reader
+	w.Write([]byte("k"))
+	r.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 1)
+	n, err := r.Read(buf)
+	if err != nil || string(buf[:n]) != "k" {
+		t.Errorf("expected k to be left unread, got %q (%v)", buf[:n], err)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	for _, tc := range []struct {
+		input    string
+		width    int
+		expected string
+	}{
+		{"hello world", 5, "hello"},
+		{"hello", 10, "hello"},
+		{"\033[31mhello\033[0m world", 3, "\033[31mhel\033[0m"},
+		{"\033[31mhi\033[0m", 5, "\033[31mhi\033[0m"},
+	} {
+		if actual := Truncate(tc.input, tc.width); actual != tc.expected {
+			t.Errorf("Truncate(%q, %d) = %q, want %q", tc.input, tc.width, actual, tc.expected)
+		}
+	}
+}
@@ -1,134 +1,320 @@
 // Package spinner contains functions for displaying progress updates
 // with a spinning icon that shows the user that progress is being made.
+//
+// Each Spinner keeps its own state, so several may be used at once.
+// The package level functions operate on a default Spinner which writes to console.Default.
 package spinner
 
 import (
 	"fmt"
+	"io"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/common-fate/cloudform/console"
 )
 
-var spin = []string{"˙", "·", ".", " "}
+// DefaultFrames are the frames used by a Spinner unless WithFrames is given
+var DefaultFrames = []string{"˙", "·", ".", " "}
 
-var hasTimer = false
+// DefaultInterval is the time between frames unless WithInterval is given
+const DefaultInterval = time.Second / 7
 
-var statuses []string
-var count = 0
-var startTime time.Time
-var paused = false
+// Spinner displays the most recently pushed status message
+// alongside a spinning icon. It is safe for concurrent use.
+type Spinner struct {
+	mu       sync.Mutex
+	term     *console.Terminal
+	frames   []string
+	interval time.Duration
 
-var lastLine = ""
+	statuses  []string
+	count     int
+	hasTimer  bool
+	startTime time.Time
+	paused    bool
+	lastLine  string
 
-func init() {
-	statuses = make([]string, 0)
+	// stop is non-nil while the animation goroutine is running
+	stop chan struct{}
+	done chan struct{}
+}
 
-	go func() {
-		for console.IsTTY {
-			if !paused && len(statuses) > 0 {
-				update()
-				count = (count + 1) % len(spin)
-			}
+// Option configures a Spinner
+type Option func(*Spinner)
 
-			time.Sleep(time.Second / 7)
-		}
-	}()
+// WithTerminal sets the terminal the spinner is drawn to. The default is console.Default.
+func WithTerminal(t *console.Terminal) Option {
+	return func(s *Spinner) {
+		s.term = t
+	}
 }
 
-func update() {
+// WithWriter draws the spinner to w. See console.NewTerminal for how w is detected.
+func WithWriter(w io.Writer) Option {
+	return WithTerminal(console.NewTerminal(w))
+}
 
-	if !console.IsTTY {
-		return
+// WithFrames sets the frames of the spinning icon
+func WithFrames(frames []string) Option {
+	return func(s *Spinner) {
+		if len(frames) > 0 {
+			s.frames = frames
+		}
 	}
+}
 
-	console.ClearLines(console.CountLines(lastLine))
-
-	if !paused && len(statuses) > 0 {
-		status := strings.TrimSpace(statuses[len(statuses)-1])
-
-		if hasTimer {
-			lastLine = fmt.Sprintf("%s%s%s %s %s",
-				console.Cyan(spin[count]),
-				console.Cyan(spin[(count+3)%len(spin)]),
-				console.Cyan(spin[(count+5)%len(spin)]),
-				time.Since(startTime).Truncate(time.Second),
-				status,
-			)
-		} else {
-			lastLine = fmt.Sprintf("%s %s%s%s",
-				status,
-				console.Cyan(spin[count]),
-				console.Cyan(spin[(count+3)%len(spin)]),
-				console.Cyan(spin[(count+5)%len(spin)]),
-			)
+// WithInterval sets the time between frames
+func WithInterval(d time.Duration) Option {
+	return func(s *Spinner) {
+		if d > 0 {
+			s.interval = d
 		}
+	}
+}
+
+// New creates a Spinner. It is not drawn until Start or Push is called.
+func New(opts ...Option) *Spinner {
+	s := &Spinner{
+		frames:   DefaultFrames,
+		interval: DefaultInterval,
+		statuses: make([]string, 0),
+	}
 
-		fmt.Print(lastLine)
+	for _, o := range opts {
+		o(s)
 	}
+
+	return s
 }
 
-// Push enables the spinner and displays the provided message
-func Push(status string) {
-	statuses = append(statuses, status)
+// Start begins animating the spinner. It does nothing if the spinner is already running
+// or it isn't drawn to a terminal.
+func (s *Spinner) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	update()
+	s.start()
 }
 
-// StartTimer enables the spinner and displays a timer counting upwards from 0
-func StartTimer(status string) {
-	startTime = time.Now()
-	hasTimer = true
+// start must be called with s.mu held
+func (s *Spinner) start() {
+	if s.stop != nil || !s.terminal().IsTTY() {
+		return
+	}
+
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
 
-	Push(status)
+	go s.run(s.stop, s.done)
 }
 
-// StopTimer disables the timer
-func StopTimer() {
-	hasTimer = false
+func (s *Spinner) run(stop, done chan struct{}) {
+	defer close(done)
 
-	Pop()
+	t := time.NewTicker(s.interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+			s.mu.Lock()
+			if !s.paused && len(s.statuses) > 0 {
+				s.update()
+				s.count = (s.count + 1) % len(s.frames)
+			}
+			s.mu.Unlock()
+		}
+	}
 }
 
-// Pop removes the move recent status and stops the spinner if there are no more messages
-func Pop() {
-	if len(statuses) > 0 {
-		statuses = statuses[:len(statuses)-1]
+// Stop empties all spinner messages, clears the spinner and stops the animation.
+// It waits for the animation to finish before returning.
+func (s *Spinner) Stop() {
+	s.mu.Lock()
+	s.statuses = make([]string, 0)
+	s.hasTimer = false
+	s.update()
+
+	done := s.halt()
+	s.mu.Unlock()
+
+	if done != nil {
+		<-done
 	}
+}
 
-	if console.IsTTY {
-		update()
+// halt signals the animation goroutine to finish and returns a channel
+// which is closed once it has. It must be called with s.mu held.
+func (s *Spinner) halt() chan struct{} {
+	if s.stop == nil {
+		return nil
 	}
+
+	done := s.done
+	close(s.stop)
+	s.stop, s.done = nil, nil
+
+	return done
 }
 
-// Pause pauses the spinner so that you can interact with the console
-func Pause() {
-	paused = true
+// update redraws the spinner. It must be called with s.mu held.
+func (s *Spinner) update() {
+	t := s.terminal()
 
-	if console.IsTTY {
-		update()
+	if !t.IsTTY() {
+		return
 	}
-}
 
-// Resume resumes the spinner
-func Resume() {
-	paused = false
+	t.ClearLines(t.CountLines(s.lastLine))
+	s.lastLine = ""
 
-	if console.IsTTY {
-		update()
+	if s.paused || len(s.statuses) == 0 {
+		return
 	}
+
+	status := strings.TrimSpace(s.statuses[len(s.statuses)-1])
+
+	icon := console.Cyan(s.frame(0)) + console.Cyan(s.frame(3)) + console.Cyan(s.frame(5))
+
+	if s.hasTimer {
+		s.lastLine = fmt.Sprintf("%s %s %s", icon, time.Since(s.startTime).Truncate(time.Second), status)
+	} else {
+		s.lastLine = fmt.Sprintf("%s %s", status, icon)
+	}
+
+	t.Print(s.lastLine)
 }
 
-// Stop empties all spinner messages and stops the spinner
-func Stop() {
-	statuses = make([]string, 0)
+func (s *Spinner) terminal() *console.Terminal {
+	if s.term == nil {
+		return console.Default
+	}
+	return s.term
+}
+
+func (s *Spinner) frame(offset int) string {
+	return s.frames[(s.count+offset)%len(s.frames)]
+}
+
+// Push enables the spinner and displays the provided message
+func (s *Spinner) Push(status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.statuses = append(s.statuses, status)
+	s.start()
+	s.update()
+}
+
+// Pop removes the most recent status. The spinner is cleared
+// and stops animating if there are no more messages.
+func (s *Spinner) Pop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.statuses) > 0 {
+		s.statuses = s.statuses[:len(s.statuses)-1]
+	}
 
-	if console.IsTTY {
-		update()
+	s.update()
+
+	if len(s.statuses) == 0 {
+		s.halt()
 	}
 }
 
-// Update causes the spinner to update - use this if you have changed the display and need the spinner to redraw
+// StartTimer enables the spinner and displays a timer counting upwards from 0
+func (s *Spinner) StartTimer(status string) {
+	s.mu.Lock()
+	s.startTime = time.Now()
+	s.hasTimer = true
+	s.mu.Unlock()
+
+	s.Push(status)
+}
+
+// StopTimer disables the timer and removes its status
+func (s *Spinner) StopTimer() {
+	s.mu.Lock()
+	s.hasTimer = false
+	s.mu.Unlock()
+
+	s.Pop()
+}
+
+// Pause hides the spinner so that you can interact with the console
+func (s *Spinner) Pause() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.paused = true
+	s.update()
+}
+
+// Resume shows the spinner again after Pause
+func (s *Spinner) Resume() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.paused = false
+	s.update()
+}
+
+// Update causes the spinner to redraw - use this if you have changed the display
+func (s *Spinner) Update() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.update()
+}
+
+var std = New()
+
+// Default returns the Spinner used by the package level functions
+func Default() *Spinner {
+	return std
+}
+
+// Push enables the default spinner and displays the provided message
+func Push(status string) {
+	std.Push(status)
+}
+
+// StartTimer enables the default spinner and displays a timer counting upwards from 0
+func StartTimer(status string) {
+	std.StartTimer(status)
+}
+
+// StopTimer disables the default spinner's timer
+func StopTimer() {
+	std.StopTimer()
+}
+
+// Pop removes the most recent status from the default spinner
+func Pop() {
+	std.Pop()
+}
+
+// Pause pauses the default spinner so that you can interact with the console
+func Pause() {
+	std.Pause()
+}
+
+// Resume resumes the default spinner
+func Resume() {
+	std.Resume()
+}
+
+// Stop empties all of the default spinner's messages and stops it
+func Stop() {
+	std.Stop()
+}
+
+// Update causes the default spinner to redraw - use this if you have changed the display
 func Update() {
-	update()
+	std.Update()
 }
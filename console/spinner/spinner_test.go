@@ -0,0 +1,42 @@
+package spinner
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/common-fate/cloudform/console"
+)
+
+func TestSpinnerConcurrentUse(t *testing.T) {
+	// The spinner only animates on a terminal, so pretend the buffer is one
+	term := console.NewTerminal(&bytes.Buffer{}, console.WithTTY(true), console.WithSize(80, 24))
+	s := New(WithTerminal(term), WithInterval(time.Millisecond))
+	s.Start()
+	if s.stop == nil {
+		t.Fatal("expected the spinner to be animating")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.Push("working")
+			s.Pause()
+			s.Resume()
+			s.Pop()
+		}()
+	}
+	wg.Wait()
+
+	if len(s.statuses) != 0 {
+		t.Errorf("expected no statuses, got %v", s.statuses)
+	}
+
+	s.Stop()
+	if s.stop != nil {
+		t.Error("expected the spinner to be stopped")
+	}
+}
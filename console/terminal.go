@@ -0,0 +1,308 @@
+package console
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/gookit/color"
+	"golang.org/x/term"
+)
+
+// Terminal renders output to a writer, which may or may not be a terminal.
+// Cursor movement is only written if the writer is a terminal which supports ANSI escape codes,
+// and colour codes are removed from output if colour is disabled.
+type Terminal struct {
+	w      io.Writer
+	fd     int
+	isTTY  bool
+	isANSI bool
+	colour bool
+
+	// width and height override the detected size if set
+	width  int
+	height int
+
+	// trackResizes is set for terminals whose size can change. Tracking starts
+	// the first time the size is needed, rather than for every Terminal created.
+	trackResizes bool
+
+	// mu guards colour, which can be changed while the terminal is written to,
+	// and the cached size. When resizes are tracked, the size is cached
+	// and updated when the terminal is resized until Close is called.
+	mu         sync.Mutex
+	tracking   bool
+	size       [2]int
+	stopResize func()
+}
+
+// TerminalOpt configures a Terminal
+type TerminalOpt func(*Terminal)
+
+// WithColour enables or disables colour, overriding the environment
+func WithColour(enabled bool) TerminalOpt {
+	return func(t *Terminal) {
+		t.colour = enabled
+	}
+}
+
+// WithSize fixes the size of the terminal rather than detecting it.
+// This is useful when rendering to a file or test buffer.
+func WithSize(width, height int) TerminalOpt {
+	return func(t *Terminal) {
+		t.width = width
+		t.height = height
+	}
+}
+
+// WithTTY overrides whether the writer is treated as a terminal which supports ANSI escape codes
+func WithTTY(isTTY bool) TerminalOpt {
+	return func(t *Terminal) {
+		t.isTTY = isTTY
+		t.isANSI = isTTY
+	}
+}
+
+// NewTerminal creates a Terminal which writes to w.
+// If w has a file descriptor (such as os.Stdout or os.Stderr) it is checked to see whether it is a terminal.
+//
+// Colour is enabled for terminals unless the NO_COLOR environment variable is set.
+// Setting FORCE_COLOR enables colour even if w is not a terminal.
+func NewTerminal(w io.Writer, opts ...TerminalOpt) *Terminal {
+	t := &Terminal{
+		w:  w,
+		fd: -1,
+	}
+
+	if f, ok := w.(interface{ Fd() uintptr }); ok {
+		t.fd = int(f.Fd())
+		t.isTTY = term.IsTerminal(t.fd)
+		t.isANSI = t.isTTY && ansiSupported(f.Fd())
+	}
+
+	t.colour = colourFromEnv(t.isTTY)
+
+	for _, o := range opts {
+		o(t)
+	}
+
+	t.trackResizes = t.isTTY && t.fd >= 0 && t.width == 0 && t.height == 0
+
+	return t
+}
+
+// colourFromEnv decides whether colour should be used, following https://no-color.org
+// and the FORCE_COLOR convention
+func colourFromEnv(isTTY bool) bool {
+	if force, ok := os.LookupEnv("FORCE_COLOR"); ok && force != "0" && force != "false" {
+		return true
+	}
+
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+
+	return isTTY
+}
+
+// Write writes p to the underlying writer, removing colour codes if colour is disabled
+func (t *Terminal) Write(p []byte) (int, error) {
+	if t.Colour() {
+		return t.w.Write(p)
+	}
+
+	_, err := io.WriteString(t.w, color.ClearCode(string(p)))
+	if err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// Print formats using the default formats for its operands and writes to the terminal
+func (t *Terminal) Print(a ...interface{}) {
+	fmt.Fprint(t, a...)
+}
+
+// Printf formats according to a format specifier and writes to the terminal
+func (t *Terminal) Printf(format string, a ...interface{}) {
+	fmt.Fprintf(t, format, a...)
+}
+
+// Println formats using the default formats for its operands and writes to the terminal,
+// followed by a newline
+func (t *Terminal) Println(a ...interface{}) {
+	fmt.Fprintln(t, a...)
+}
+
+// IsTTY returns true if the terminal's writer is connected to a true terminal
+func (t *Terminal) IsTTY() bool {
+	return t.isTTY
+}
+
+// Colour returns true if colour codes are written to the terminal
+func (t *Terminal) Colour() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.colour
+}
+
+// SetColour enables or disables colour.
+// Setting it on Default also changes whether the colour helpers, such as Red, add colour codes.
+func (t *Terminal) SetColour(enabled bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.colour = enabled
+}
+
+// Size returns the width and height of the terminal in characters.
+// Zero is returned for both if the size can't be determined.
+func (t *Terminal) Size() (int, int) {
+	if t.width > 0 || t.height > 0 {
+		return t.width, t.height
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.tracking && t.trackResizes {
+		t.tracking = t.trackResize()
+	}
+
+	if t.tracking {
+		return t.size[0], t.size[1]
+	}
+
+	return t.querySize()
+}
+
+// Close stops tracking resizes of the terminal. The terminal can still be used
+// afterwards, but its size is queried each time it is needed.
+func (t *Terminal) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.stopResize != nil {
+		t.stopResize()
+		t.stopResize = nil
+	}
+	t.tracking = false
+	t.trackResizes = false
+
+	return nil
+}
+
+// querySize asks the operating system for the size of the terminal
+func (t *Terminal) querySize() (int, int) {
+	if t.fd < 0 {
+		return 0, 0
+	}
+
+	w, h, err := term.GetSize(t.fd)
+	if err != nil {
+		return 0, 0
+	}
+
+	return w, h
+}
+
+// refreshSize updates the cached size of the terminal after it has been resized
+func (t *Terminal) refreshSize() {
+	w, h := t.querySize()
+
+	t.mu.Lock()
+	t.size = [2]int{w, h}
+	t.mu.Unlock()
+}
+
+// Width returns the width of the terminal in characters
+func (t *Terminal) Width() int {
+	w, _ := t.Size()
+	return w
+}
+
+// CountLines returns the number of lines that would be taken up by the given string.
+// Lines which are wider than the terminal are counted as wrapping, taking the display
+// width of wide characters and tabs into account.
+func (t *Terminal) CountLines(input string) int {
+	input = color.ClearCode(input)
+
+	if input == "" {
+		return 0
+	}
+
+	w := t.Width()
+
+	if w == 0 {
+		return 0
+	}
+
+	count := 1
+	col := 0
+	walkColumns(input, w, func(r rune, start, width int) {
+		if r == '\n' {
+			count++
+			col = 0
+			return
+		}
+		if start == 0 && col > 0 {
+			count++
+		}
+		col = start + width
+	})
+
+	return count
+}
+
+// ClearLine removes all text from the current line and puts the cursor on the left
+func (t *Terminal) ClearLine() {
+	if t.isTTY && t.isANSI {
+		io.WriteString(t.w, "\033[G\033[K")
+	} else {
+		io.WriteString(t.w, "\n")
+	}
+}
+
+// ClearLines removes all text from the previous n lines (starting with the current line) and puts the cursor on the left
+func (t *Terminal) ClearLines(n int) {
+	if !t.isTTY {
+		return
+	}
+
+	for i := 0; i < n; i++ {
+		t.ClearLine()
+		if i < n-1 && t.isANSI {
+			io.WriteString(t.w, "\033[F")
+		}
+	}
+}
+
+// IsInteractive returns true if the terminal supports ANSI escape codes and stdin is a terminal
+func (t *Terminal) IsInteractive() bool {
+	return t.isTTY && t.isANSI && term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// EnterFullScreen switches to the terminal's alternate screen, hides the cursor and puts
+// stdin into raw mode so that key presses can be read with ReadStdinKeys.
+// The returned function restores the terminal and must always be called.
+func (t *Terminal) EnterFullScreen() (func(), error) {
+	state, err := term.MakeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		return nil, fmt.Errorf("unable to enter full screen mode: %w", err)
+	}
+
+	io.WriteString(t.w, "\033[?1049h\033[?25l\033[H\033[2J")
+
+	return func() {
+		io.WriteString(t.w, "\033[?25h\033[?1049l")
+		_ = term.Restore(int(os.Stdin.Fd()), state)
+	}, nil
+}
+
+// DrawScreen redraws the whole screen with the given lines. See DrawScreen.
+func (t *Terminal) DrawScreen(lines []string) {
+	DrawScreen(t, lines)
+}
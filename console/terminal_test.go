@@ -0,0 +1,78 @@
+package console
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTerminalColour(t *testing.T) {
+	var buf bytes.Buffer
+	term := NewTerminal(&buf, WithColour(false))
+	term.Print("\033[31mred\033[0m")
+
+	if buf.String() != "red" {
+		t.Errorf("expected colour codes to be removed, got %q", buf.String())
+	}
+
+	buf.Reset()
+	term.SetColour(true)
+	term.Print("\033[31mred\033[0m")
+
+	if buf.String() != "\033[31mred\033[0m" {
+		t.Errorf("expected colour codes to be kept, got %q", buf.String())
+	}
+}
+
+func TestTerminalEnv(t *testing.T) {
+	// FORCE_COLOR takes precedence, so clear any value the tests were run with
+	t.Setenv("FORCE_COLOR", "0")
+	t.Setenv("NO_COLOR", "1")
+	if NewTerminal(&bytes.Buffer{}, WithTTY(true)).Colour() {
+		t.Error("expected NO_COLOR to disable colour")
+	}
+
+	t.Setenv("FORCE_COLOR", "1")
+	if !NewTerminal(&bytes.Buffer{}).Colour() {
+		t.Error("expected FORCE_COLOR to enable colour")
+	}
+}
+
+func TestTerminalClearLines(t *testing.T) {
+	var buf bytes.Buffer
+	term := NewTerminal(&buf, WithTTY(true), WithSize(10, 5))
+
+	if n := term.CountLines("hello world\nhi"); n != 3 {
+		t.Errorf("expected 3 lines, got %d", n)
+	}
+
+	term.ClearLines(2)
+	if expected := "\033[G\033[K\033[F\033[G\033[K"; buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+
+	buf.Reset()
+	NewTerminal(&buf).ClearLines(2)
+	if buf.Len() != 0 {
+		t.Errorf("expected nothing to be written to a non-terminal, got %q", buf.String())
+	}
+}
+
+func TestTerminalTrackResize(t *testing.T) {
+	term := &Terminal{w: &bytes.Buffer{}, fd: -1, trackResizes: true}
+	if term.tracking {
+		t.Fatal("expected resizes not to be tracked before the size is needed")
+	}
+
+	term.Size()
+	if term.tracking != (term.stopResize != nil) {
+		t.Errorf("expected a stop function while resizes are tracked")
+	}
+
+	if err := term.Close(); err != nil {
+		t.Fatal(err)
+	}
+	term.Size()
+	if term.tracking || term.stopResize != nil {
+		t.Error("expected Close to stop tracking resizes")
+	}
+}
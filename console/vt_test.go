@@ -0,0 +1,187 @@
+package console
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// virtualTerminal is a minimal terminal emulator for testing redraws.
+// It understands the escape codes written by Terminal, wraps long lines and
+// draws wide characters across two cells. It has no height limit.
+type virtualTerminal struct {
+	width    int
+	rows     [][]rune
+	row, col int
+}
+
+// wideTail marks the second cell of a wide character
+const wideTail = -1
+
+func newVirtualTerminal(width int) *virtualTerminal {
+	return &virtualTerminal{
+		width: width,
+		rows:  [][]rune{make([]rune, 0)},
+	}
+}
+
+func (v *virtualTerminal) Write(p []byte) (int, error) {
+	runes := []rune(string(p))
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		switch r {
+		case '\033':
+			j := i + 2
+			for j < len(runes) && (runes[j] < '@' || runes[j] > '~') {
+				j++
+			}
+			v.escape(runes[j])
+			i = j
+		case '\n':
+			v.newline()
+		case '\r':
+			v.col = 0
+		case '\t':
+			next := (v.col/tabWidth + 1) * tabWidth
+			if next >= v.width {
+				next = v.width - 1
+			}
+			if next > v.col {
+				v.col = next
+			}
+		default:
+			v.put(r)
+		}
+	}
+
+	return len(p), nil
+}
+
+func (v *virtualTerminal) escape(final rune) {
+	switch final {
+	case 'G':
+		v.col = 0
+	case 'F':
+		if v.row > 0 {
+			v.row--
+		}
+		v.col = 0
+	case 'K':
+		line := v.rows[v.row]
+		if v.col < len(line) {
+			v.rows[v.row] = line[:v.col]
+		}
+	}
+}
+
+func (v *virtualTerminal) newline() {
+	v.row++
+	v.col = 0
+	if v.row == len(v.rows) {
+		v.rows = append(v.rows, make([]rune, 0))
+	}
+}
+
+func (v *virtualTerminal) put(r rune) {
+	w := RuneWidth(r)
+	if w == 0 {
+		return
+	}
+
+	// The cursor waits at the end of a full row until something is printed
+	if v.col+w > v.width {
+		v.newline()
+	}
+
+	line := v.rows[v.row]
+	for len(line) < v.col+w {
+		line = append(line, ' ')
+	}
+	line[v.col] = r
+	if w == 2 {
+		line[v.col+1] = wideTail
+	}
+	v.rows[v.row] = line
+	v.col += w
+}
+
+// Screen returns the visible text with trailing spaces and empty rows removed
+func (v *virtualTerminal) Screen() string {
+	lines := make([]string, len(v.rows))
+	for i, row := range v.rows {
+		b := strings.Builder{}
+		for _, r := range row {
+			if r != wideTail {
+				b.WriteRune(r)
+			}
+		}
+		lines[i] = strings.TrimRight(b.String(), " ")
+	}
+
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n")
+}
+
+func TestRedraw(t *testing.T) {
+	vt := newVirtualTerminal(20)
+	term := NewTerminal(vt, WithTTY(true), WithSize(20, 10), WithColour(true))
+
+	term.Print("$ cloudform deploy\n")
+
+	frames := []string{
+		"Stack: CREATE_IN_PROGRESS\n",
+		"スタック: リソースを作成しています\nBucket \033[32m✓\033[0m\n",
+		"🚀 deploying 🚀 rocket ships\n",
+		"a\tb\tc\td\te\n",
+		"exactly twenty chars\nfits\n",
+		"👩‍💻 done\n",
+	}
+
+	screens := make([]string, 0)
+	last := ""
+	for _, frame := range frames {
+		term.ClearLines(term.CountLines(last))
+		term.Print(frame)
+		last = frame
+
+		screens = append(screens, vt.Screen())
+	}
+
+	term.ClearLines(term.CountLines(last))
+	screens = append(screens, vt.Screen())
+
+	expected, err := os.ReadFile("testdata/redraw.golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual := strings.Join(screens, "\n---\n") + "\n"
+
+	if d := cmp.Diff(string(expected), actual); d != "" {
+		t.Error(d)
+	}
+}
+
+func TestStringWidth(t *testing.T) {
+	for _, tc := range []struct {
+		input    string
+		expected int
+	}{
+		{"hello", 5},
+		{"\033[31mred\033[0m", 3},
+		{"日本語", 6},
+		{"✓ done", 6},
+		{"✅ done", 7},
+		{"é", 1},
+		{"👩‍💻", 2},
+		{"a\tb", 9},
+		{"short\nlonger line", 11},
+	} {
+		if actual := StringWidth(tc.input); actual != tc.expected {
+			t.Errorf("StringWidth(%q) = %d, want %d", tc.input, actual, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package console
+
+import (
+	"sort"
+
+	"github.com/gookit/color"
+)
+
+// tabWidth is the distance between tab stops
+const tabWidth = 8
+
+type runeRange struct {
+	first, last rune
+}
+
+// wideRunes are displayed in two columns. They are the East Asian Wide and Fullwidth
+// characters and the emoji which are presented as pictures by default.
+var wideRunes = []runeRange{
+	{0x1100, 0x115F},
+	{0x231A, 0x231B},
+	{0x2329, 0x232A},
+	{0x23E9, 0x23EC},
+	{0x23F0, 0x23F0},
+	{0x23F3, 0x23F3},
+	{0x25FD, 0x25FE},
+	{0x2614, 0x2615},
+	{0x2648, 0x2653},
+	{0x267F, 0x267F},
+	{0x2693, 0x2693},
+	{0x26A1, 0x26A1},
+	{0x26AA, 0x26AB},
+	{0x26BD, 0x26BE},
+	{0x26C4, 0x26C5},
+	{0x26CE, 0x26CE},
+	{0x26D4, 0x26D4},
+	{0x26EA, 0x26EA},
+	{0x26F2, 0x26F3},
+	{0x26F5, 0x26F5},
+	{0x26FA, 0x26FA},
+	{0x26FD, 0x26FD},
+	{0x2705, 0x2705},
+	{0x270A, 0x270B},
+	{0x2728, 0x2728},
+	{0x274C, 0x274C},
+	{0x274E, 0x274E},
+	{0x2753, 0x2755},
+	{0x2757, 0x2757},
+	{0x2795, 0x2797},
+	{0x27B0, 0x27B0},
+	{0x27BF, 0x27BF},
+	{0x2B1B, 0x2B1C},
+	{0x2B50, 0x2B50},
+	{0x2B55, 0x2B55},
+	{0x2E80, 0x303E},
+	{0x3041, 0x33FF},
+	{0x3400, 0x4DBF},
+	{0x4E00, 0x9FFF},
+	{0xA000, 0xA4CF},
+	{0xA960, 0xA97F},
+	{0xAC00, 0xD7A3},
+	{0xF900, 0xFAFF},
+	{0xFE10, 0xFE19},
+	{0xFE30, 0xFE6F},
+	{0xFF00, 0xFF60},
+	{0xFFE0, 0xFFE6},
+	{0x16FE0, 0x16FE4},
+	{0x17000, 0x18AFF},
+	{0x1B000, 0x1B2FF},
+	{0x1F004, 0x1F004},
+	{0x1F0CF, 0x1F0CF},
+	{0x1F18E, 0x1F18E},
+	{0x1F191, 0x1F19A},
+	{0x1F200, 0x1F251},
+	{0x1F300, 0x1F3FA},
+	{0x1F400, 0x1F64F},
+	{0x1F680, 0x1F6FF},
+	{0x1F7E0, 0x1F7EB},
+	{0x1F90C, 0x1F9FF},
+	{0x1FA70, 0x1FAFF},
+	{0x20000, 0x3FFFD},
+}
+
+// zeroWidthRunes take up no columns. They are combining marks, format characters,
+// variation selectors and emoji skin tone modifiers.
+var zeroWidthRunes = []runeRange{
+	{0x0300, 0x036F},
+	{0x0483, 0x0489},
+	{0x0591, 0x05BD},
+	{0x0610, 0x061A},
+	{0x064B, 0x065F},
+	{0x1AB0, 0x1AFF},
+	{0x1DC0, 0x1DFF},
+	{0x200B, 0x200F},
+	{0x2028, 0x202E},
+	{0x2060, 0x2064},
+	{0x20D0, 0x20FF},
+	{0xFE00, 0xFE0F},
+	{0xFE20, 0xFE2F},
+	{0xFEFF, 0xFEFF},
+	{0x1F3FB, 0x1F3FF},
+	{0xE0000, 0xE007F},
+	{0xE0100, 0xE01EF},
+}
+
+func inRanges(r rune, ranges []runeRange) bool {
+	i := sort.Search(len(ranges), func(i int) bool {
+		return ranges[i].last >= r
+	})
+	return i < len(ranges) && ranges[i].first <= r
+}
+
+// RuneWidth returns the number of columns r takes up when displayed in a terminal.
+// Control characters, including tab, have a width of 0.
+func RuneWidth(r rune) int {
+	switch {
+	case r < 0x20, r >= 0x7F && r < 0xA0:
+		return 0
+	case r < 0x300:
+		return 1
+	case inRanges(r, zeroWidthRunes):
+		return 0
+	case inRanges(r, wideRunes):
+		return 2
+	default:
+		return 1
+	}
+}
+
+// StringWidth returns the number of columns s takes up when displayed in a terminal.
+// Colour codes are ignored and tabs are expanded to the next tab stop.
+// If s contains several lines, the width of the widest line is returned.
+func StringWidth(s string) int {
+	max := 0
+	col := 0
+
+	walkColumns(color.ClearCode(s), 0, func(r rune, start, width int) {
+		if r == '\n' {
+			col = 0
+			return
+		}
+		col = start + width
+		if col > max {
+			max = col
+		}
+	})
+
+	return max
+}
+
+// walkColumns calls fn with each rune of s, the column it starts at and its width.
+// If lineWidth is greater than zero, lines are wrapped at that width the way a terminal does:
+// a wide rune that doesn't fit at the end of a row moves to the next row, and tabs stop at
+// the last column. A rune which starts a new row is passed with a start column of 0.
+// Runes which join an emoji sequence are given a width of 0.
+func walkColumns(s string, lineWidth int, fn func(r rune, start, width int)) {
+	col := 0
+	joined := false
+
+	for _, r := range s {
+		if r == '\n' {
+			fn(r, col, 0)
+			col = 0
+			continue
+		}
+
+		w := RuneWidth(r)
+
+		if r == '\t' {
+			next := (col/tabWidth + 1) * tabWidth
+			if lineWidth > 0 && next >= lineWidth {
+				next = lineWidth - 1
+				if col > next {
+					next = col
+				}
+			}
+			w = next - col
+		}
+
+		// The rune after a zero width joiner is drawn as part of the previous one
+		if joined {
+			w = 0
+		}
+		joined = r == 0x200D
+
+		if lineWidth > 0 && w > 0 && col+w > lineWidth {
+			col = 0
+		}
+
+		fn(r, col, w)
+		col += w
+	}
+}
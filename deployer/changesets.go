@@ -0,0 +1,46 @@
+package deployer
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/smithy-go/ptr"
+	"github.com/pkg/errors"
+)
+
+// PruneChangeSets deletes change sets belonging to the named stack
+// which were created more than olderThan ago. Change sets which are
+// currently being executed are left alone.
+// The names of the deleted change sets are returned.
+func (b *Deployer) PruneChangeSets(ctx context.Context, stackName string, olderThan time.Duration) ([]string, error) {
+	summaries, err := b.cloudformClient.ListChangeSets(ctx, stackName)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing changesets")
+	}
+
+	cutoff := time.Now().Add(-olderThan)
+	pruned := make([]string, 0)
+
+	for _, summary := range summaries {
+		if summary.CreationTime == nil || summary.CreationTime.After(cutoff) {
+			continue
+		}
+
+		switch summary.ExecutionStatus {
+		case types.ExecutionStatusExecuteInProgress, types.ExecutionStatusExecuteComplete:
+			continue
+		}
+
+		name := ptr.ToString(summary.ChangeSetName)
+
+		err = b.cloudformClient.DeleteChangeSet(ctx, stackName, name)
+		if err != nil {
+			return pruned, errors.Wrapf(err, "deleting changeset %s", name)
+		}
+
+		pruned = append(pruned, name)
+	}
+
+	return pruned, nil
+}
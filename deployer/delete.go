@@ -0,0 +1,312 @@
+package deployer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws-cloudformation/rain/cft/parse"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	ecrtypes "github.com/aws/aws-sdk-go-v2/service/ecr/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go/ptr"
+	"github.com/common-fate/clio"
+	"github.com/common-fate/cloudform/console"
+	"github.com/pkg/errors"
+)
+
+// DeleteResource is a resource which is affected by deleting a stack
+type DeleteResource struct {
+	// StackName is the name of the stack containing the resource
+	StackName string
+	// Path is the logical ID of the resource prefixed by
+	// the logical IDs of any nested stacks, separated by "/"
+	Path         string
+	LogicalID    string
+	PhysicalID   string
+	ResourceType string
+	Status       string
+	// DeletionPolicy is the resource's DeletionPolicy attribute.
+	// It is "Delete" if the template doesn't specify one.
+	DeletionPolicy string
+	// Retained is true if the resource will be kept, either
+	// because of its DeletionPolicy or because it was passed in RetainResources
+	Retained bool
+}
+
+// PreDeleteHook is run with the stack's resources before the stack is deleted
+type PreDeleteHook func(ctx context.Context, resources []DeleteResource) error
+
+// DeletePreview lists every resource in the stack, including resources in nested stacks,
+// along with its DeletionPolicy. Resources listed in retain are marked as retained.
+func (b *Deployer) DeletePreview(ctx context.Context, stackName string, retain ...string) ([]DeleteResource, error) {
+	return b.deletePreview(ctx, stackName, "", retain)
+}
+
+func (b *Deployer) deletePreview(ctx context.Context, stackName, prefix string, retain []string) ([]DeleteResource, error) {
+	resources, err := b.cloudformClient.GetStackResources(ctx, stackName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "listing resources of %s", stackName)
+	}
+
+	// We ignore errors because it just means every resource uses the default policy
+	policies := make(map[string]string)
+	if body, err := b.cloudformClient.GetTemplate(ctx, stackName, ""); err == nil {
+		policies = deletionPolicies(body)
+	}
+
+	out := make([]DeleteResource, 0)
+
+	for _, resource := range resources {
+		r := DeleteResource{
+			StackName:      stackName,
+			Path:           prefix + ptr.ToString(resource.LogicalResourceId),
+			LogicalID:      ptr.ToString(resource.LogicalResourceId),
+			PhysicalID:     ptr.ToString(resource.PhysicalResourceId),
+			ResourceType:   ptr.ToString(resource.ResourceType),
+			Status:         string(resource.ResourceStatus),
+			DeletionPolicy: "Delete",
+		}
+
+		if policy, ok := policies[r.LogicalID]; ok {
+			r.DeletionPolicy = policy
+		}
+
+		r.Retained = r.DeletionPolicy == "Retain" || r.DeletionPolicy == "RetainExceptOnCreate" || r.Status == "DELETE_SKIPPED"
+		if prefix == "" {
+			for _, id := range retain {
+				if id == r.LogicalID {
+					r.Retained = true
+				}
+			}
+		}
+
+		out = append(out, r)
+
+		if r.ResourceType == "AWS::CloudFormation::Stack" && r.PhysicalID != "" && !r.Retained {
+			nested, err := b.deletePreview(ctx, r.PhysicalID, r.Path+"/", nil)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, nested...)
+		}
+	}
+
+	return out, nil
+}
+
+// deletionPolicies returns the DeletionPolicy of each resource in a template which sets one
+func deletionPolicies(body string) map[string]string {
+	out := make(map[string]string)
+
+	t, err := parse.String(body)
+	if err != nil {
+		return out
+	}
+
+	resources, _ := t.Map()["Resources"].(map[string]interface{})
+	for id, r := range resources {
+		resource, _ := r.(map[string]interface{})
+		if policy, ok := resource["DeletionPolicy"].(string); ok {
+			out[id] = policy
+		}
+	}
+
+	return out
+}
+
+// FormatDeletePreview returns a pretty representation of the resources affected by a delete
+func FormatDeletePreview(resources []DeleteResource) string {
+	out := strings.Builder{}
+
+	for _, r := range resources {
+		depth := strings.Count(r.Path, "/")
+		line := fmt.Sprintf("%s%s %s", strings.Repeat("  ", depth), r.ResourceType, r.LogicalID)
+		if r.PhysicalID != "" && r.ResourceType != "AWS::CloudFormation::Stack" {
+			line += console.Grey(fmt.Sprintf(" (%s)", r.PhysicalID))
+		}
+
+		switch {
+		case r.Retained:
+			out.WriteString(console.Grey(fmt.Sprintf("  = %s # retained", line)))
+		case r.DeletionPolicy == "Snapshot":
+			out.WriteString(console.Yellow(fmt.Sprintf("  - %s # snapshot", line)))
+		default:
+			out.WriteString(console.Red(fmt.Sprintf("  - %s", line)))
+		}
+
+		out.WriteString("\n")
+	}
+
+	return strings.TrimRight(out.String(), "\n")
+}
+
+// EmptyS3Buckets returns a PreDeleteHook which deletes every object version
+// from the stack's S3 buckets so that CloudFormation is able to delete them.
+// Retained buckets are left untouched.
+func EmptyS3Buckets(cfg aws.Config) PreDeleteHook {
+	client := s3.NewFromConfig(cfg)
+
+	return func(ctx context.Context, resources []DeleteResource) error {
+		for _, r := range resources {
+			if r.ResourceType != "AWS::S3::Bucket" || r.Retained || r.PhysicalID == "" {
+				continue
+			}
+
+			clio.Infof("Emptying S3 bucket %s", r.PhysicalID)
+
+			err := emptyBucket(ctx, client, r.PhysicalID)
+			if err != nil {
+				return errors.Wrapf(err, "emptying bucket %s", r.PhysicalID)
+			}
+		}
+
+		return nil
+	}
+}
+
+// bucketClient is the part of the S3 client used by emptyBucket
+type bucketClient interface {
+	ListObjectVersions(ctx context.Context, params *s3.ListObjectVersionsInput, optFns ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error)
+	DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error)
+}
+
+func emptyBucket(ctx context.Context, client bucketClient, bucket string) error {
+	input := &s3.ListObjectVersionsInput{
+		Bucket: &bucket,
+	}
+
+	for {
+		res, err := client.ListObjectVersions(ctx, input)
+		if err != nil {
+			return err
+		}
+
+		objects := make([]s3types.ObjectIdentifier, 0, len(res.Versions)+len(res.DeleteMarkers))
+		for _, v := range res.Versions {
+			objects = append(objects, s3types.ObjectIdentifier{Key: v.Key, VersionId: v.VersionId})
+		}
+		for _, m := range res.DeleteMarkers {
+			objects = append(objects, s3types.ObjectIdentifier{Key: m.Key, VersionId: m.VersionId})
+		}
+
+		if len(objects) > 0 {
+			deleted, err := client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+				Bucket: &bucket,
+				Delete: &s3types.Delete{Objects: objects, Quiet: true},
+			})
+			if err != nil {
+				return err
+			}
+
+			// Objects which couldn't be deleted are reported rather than failing the request
+			if len(deleted.Errors) > 0 {
+				e := deleted.Errors[0]
+				return errors.Errorf("deleting object %s: %s: %s%s", ptr.ToString(e.Key), ptr.ToString(e.Code), ptr.ToString(e.Message), moreFailures(len(deleted.Errors)))
+			}
+		}
+
+		if !res.IsTruncated {
+			return nil
+		}
+
+		input.KeyMarker = res.NextKeyMarker
+		input.VersionIdMarker = res.NextVersionIdMarker
+	}
+}
+
+// EmptyECRRepositories returns a PreDeleteHook which deletes every image
+// from the stack's ECR repositories so that CloudFormation is able to delete them.
+// Retained repositories are left untouched.
+func EmptyECRRepositories(cfg aws.Config) PreDeleteHook {
+	client := ecr.NewFromConfig(cfg)
+
+	return func(ctx context.Context, resources []DeleteResource) error {
+		for _, r := range resources {
+			if r.ResourceType != "AWS::ECR::Repository" || r.Retained || r.PhysicalID == "" {
+				continue
+			}
+
+			clio.Infof("Emptying ECR repository %s", r.PhysicalID)
+
+			err := emptyRepository(ctx, client, r.PhysicalID)
+			if err != nil {
+				return errors.Wrapf(err, "emptying repository %s", r.PhysicalID)
+			}
+		}
+
+		return nil
+	}
+}
+
+// repositoryClient is the part of the ECR client used by emptyRepository
+type repositoryClient interface {
+	ecr.ListImagesAPIClient
+	BatchDeleteImage(ctx context.Context, params *ecr.BatchDeleteImageInput, optFns ...func(*ecr.Options)) (*ecr.BatchDeleteImageOutput, error)
+}
+
+// maxBatchDeleteImages is the most images BatchDeleteImage accepts in one request
+const maxBatchDeleteImages = 100
+
+// emptyRepository deletes every image in the repository. All of the images are listed
+// before any are deleted, as deleting images while paging through them can skip some.
+func emptyRepository(ctx context.Context, client repositoryClient, repository string) error {
+	p := ecr.NewListImagesPaginator(client, &ecr.ListImagesInput{
+		RepositoryName: &repository,
+		MaxResults:     aws.Int32(maxBatchDeleteImages),
+	})
+
+	var images []ecrtypes.ImageIdentifier
+	for p.HasMorePages() {
+		res, err := p.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		images = append(images, res.ImageIds...)
+	}
+
+	for len(images) > 0 {
+		batch := images
+		if len(batch) > maxBatchDeleteImages {
+			batch = batch[:maxBatchDeleteImages]
+		}
+		images = images[len(batch):]
+
+		deleted, err := client.BatchDeleteImage(ctx, &ecr.BatchDeleteImageInput{
+			RepositoryName: &repository,
+			ImageIds:       batch,
+		})
+		if err != nil {
+			return err
+		}
+
+		// Images which couldn't be deleted are reported rather than failing the request
+		if len(deleted.Failures) > 0 {
+			f := deleted.Failures[0]
+			return errors.Errorf("deleting image %s: %s: %s%s", imageName(f.ImageId), f.FailureCode, ptr.ToString(f.FailureReason), moreFailures(len(deleted.Failures)))
+		}
+	}
+
+	return nil
+}
+
+// imageName returns an image's tag, or its digest if it isn't tagged
+func imageName(id *ecrtypes.ImageIdentifier) string {
+	if id == nil {
+		return "unknown"
+	}
+	if id.ImageTag != nil {
+		return *id.ImageTag
+	}
+	return ptr.ToString(id.ImageDigest)
+}
+
+// moreFailures describes the failures after the first of n, if there are any
+func moreFailures(n int) string {
+	if n <= 1 {
+		return ""
+	}
+	return fmt.Sprintf(" (and %d more)", n-1)
+}
@@ -0,0 +1,239 @@
+package deployer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	ecrtypes "github.com/aws/aws-sdk-go-v2/service/ecr/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go/ptr"
+	"github.com/google/go-cmp/cmp"
+)
+
+const deleteTemplate = `
+Resources:
+  Bucket:
+    Type: AWS::S3::Bucket
+    DeletionPolicy: Retain
+  Database:
+    Type: AWS::RDS::DBInstance
+    DeletionPolicy: Snapshot
+  Queue:
+    Type: AWS::SQS::Queue
+`
+
+func TestDeletionPolicies(t *testing.T) {
+	expected := map[string]string{
+		"Bucket":   "Retain",
+		"Database": "Snapshot",
+	}
+
+	if d := cmp.Diff(expected, deletionPolicies(deleteTemplate)); d != "" {
+		t.Error(d)
+	}
+}
+
+func TestDeleteToken(t *testing.T) {
+	stack := types.Stack{
+		StackId:     ptr.String("arn:aws:cloudformation:us-east-1:123456789012:stack/app/1"),
+		StackName:   ptr.String("app"),
+		StackStatus: types.StackStatusUpdateComplete,
+	}
+
+	first := deleteToken(stack, nil)
+	if first != deleteToken(stack, nil) {
+		t.Error("expected the token to be stable for the same stack state")
+	}
+
+	stack.StackStatus = types.StackStatusDeleteFailed
+	if first == deleteToken(stack, nil) {
+		t.Error("expected a new token after the deletion failed")
+	}
+	if deleteToken(stack, nil) == deleteToken(stack, []string{"Bucket"}) {
+		t.Error("expected a new token when retaining resources")
+	}
+}
+
+// fakeBucket is a bucketClient with one page of object versions
+type fakeBucket struct {
+	versions []s3types.ObjectVersion
+	errors   []s3types.Error
+	deleted  []string
+}
+
+func (f *fakeBucket) ListObjectVersions(ctx context.Context, params *s3.ListObjectVersionsInput, optFns ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error) {
+	return &s3.ListObjectVersionsOutput{Versions: f.versions}, nil
+}
+
+func (f *fakeBucket) DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	for _, o := range params.Delete.Objects {
+		f.deleted = append(f.deleted, ptr.ToString(o.Key))
+	}
+	return &s3.DeleteObjectsOutput{Errors: f.errors}, nil
+}
+
+func TestEmptyBucket(t *testing.T) {
+	versions := []s3types.ObjectVersion{
+		{Key: ptr.String("a.txt"), VersionId: ptr.String("1")},
+		{Key: ptr.String("b.txt"), VersionId: ptr.String("1")},
+	}
+
+	for _, tc := range []struct {
+		name   string
+		errors []s3types.Error
+		err    string
+	}{
+		{name: "deleted"},
+		{
+			name: "object fails",
+			errors: []s3types.Error{
+				{Key: ptr.String("b.txt"), Code: ptr.String("AccessDenied"), Message: ptr.String("Access Denied")},
+			},
+			err: "deleting object b.txt: AccessDenied: Access Denied",
+		},
+		{
+			name: "objects fail",
+			errors: []s3types.Error{
+				{Key: ptr.String("a.txt"), Code: ptr.String("AccessDenied"), Message: ptr.String("Access Denied")},
+				{Key: ptr.String("b.txt"), Code: ptr.String("AccessDenied"), Message: ptr.String("Access Denied")},
+			},
+			err: "deleting object a.txt: AccessDenied: Access Denied (and 1 more)",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &fakeBucket{versions: versions, errors: tc.errors}
+			err := emptyBucket(context.Background(), client, "bucket")
+
+			if d := cmp.Diff([]string{"a.txt", "b.txt"}, client.deleted); d != "" {
+				t.Error(d)
+			}
+			checkError(t, err, tc.err)
+		})
+	}
+}
+
+// fakeRepository is a repositoryClient which pages through its images
+// and removes them when they are deleted, like ECR does
+type fakeRepository struct {
+	images   []ecrtypes.ImageIdentifier
+	failures []ecrtypes.ImageFailure
+	deleted  int
+}
+
+func (f *fakeRepository) ListImages(ctx context.Context, params *ecr.ListImagesInput, optFns ...func(*ecr.Options)) (*ecr.ListImagesOutput, error) {
+	start := 0
+	if params.NextToken != nil {
+		start, _ = strconv.Atoi(*params.NextToken)
+	}
+	if start > len(f.images) {
+		start = len(f.images)
+	}
+
+	end := len(f.images)
+	if params.MaxResults != nil && start+int(*params.MaxResults) < end {
+		end = start + int(*params.MaxResults)
+	}
+
+	res := &ecr.ListImagesOutput{ImageIds: f.images[start:end]}
+	if end < len(f.images) {
+		res.NextToken = ptr.String(strconv.Itoa(end))
+	}
+	return res, nil
+}
+
+func (f *fakeRepository) BatchDeleteImage(ctx context.Context, params *ecr.BatchDeleteImageInput, optFns ...func(*ecr.Options)) (*ecr.BatchDeleteImageOutput, error) {
+	if len(params.ImageIds) > 100 {
+		return nil, errors.New("too many images in one request")
+	}
+
+	deleted := map[string]bool{}
+	for _, id := range params.ImageIds {
+		deleted[*id.ImageDigest] = true
+	}
+
+	var kept []ecrtypes.ImageIdentifier
+	for _, id := range f.images {
+		if !deleted[*id.ImageDigest] {
+			kept = append(kept, id)
+		}
+	}
+	f.images = kept
+
+	f.deleted += len(params.ImageIds)
+	return &ecr.BatchDeleteImageOutput{Failures: f.failures}, nil
+}
+
+func TestEmptyRepository(t *testing.T) {
+	images := []ecrtypes.ImageIdentifier{
+		{ImageTag: ptr.String("latest"), ImageDigest: ptr.String("sha256:aaaa")},
+		{ImageDigest: ptr.String("sha256:bbbb")},
+	}
+
+	var many []ecrtypes.ImageIdentifier
+	for i := 0; i < 250; i++ {
+		many = append(many, ecrtypes.ImageIdentifier{ImageDigest: ptr.String(fmt.Sprintf("sha256:%04d", i))})
+	}
+
+	for _, tc := range []struct {
+		name     string
+		images   []ecrtypes.ImageIdentifier
+		failures []ecrtypes.ImageFailure
+		err      string
+	}{
+		{name: "deleted", images: images},
+		{name: "several pages", images: many},
+		{
+			name:   "untagged image fails",
+			images: images,
+			failures: []ecrtypes.ImageFailure{
+				{ImageId: &images[1], FailureCode: ecrtypes.ImageFailureCodeImageReferencedByManifestList, FailureReason: ptr.String("Image is referenced by a manifest list")},
+			},
+			err: "deleting image sha256:bbbb: ImageReferencedByManifestList: Image is referenced by a manifest list",
+		},
+		{
+			name:   "images fail",
+			images: images,
+			failures: []ecrtypes.ImageFailure{
+				{ImageId: &images[0], FailureCode: ecrtypes.ImageFailureCodeKmsError, FailureReason: ptr.String("KMS key is disabled")},
+				{ImageId: &images[1], FailureCode: ecrtypes.ImageFailureCodeKmsError, FailureReason: ptr.String("KMS key is disabled")},
+			},
+			err: "deleting image latest: KmsError: KMS key is disabled (and 1 more)",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &fakeRepository{images: tc.images, failures: tc.failures}
+			err := emptyRepository(context.Background(), client, "repository")
+
+			if client.deleted != len(tc.images) {
+				t.Errorf("expected %d images to be deleted, got %d", len(tc.images), client.deleted)
+			}
+			if tc.err == "" && len(client.images) != 0 {
+				t.Errorf("expected the repository to be empty, %d images are left", len(client.images))
+			}
+			checkError(t, err, tc.err)
+		})
+	}
+}
+
+// checkError fails the test unless err matches expected, where an empty string means no error
+func checkError(t *testing.T, err error, expected string) {
+	t.Helper()
+
+	if expected == "" {
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		return
+	}
+
+	if err == nil || !strings.Contains(err.Error(), expected) {
+		t.Errorf("expected an error containing %q, got %v", expected, err)
+	}
+}
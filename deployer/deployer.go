@@ -3,7 +3,6 @@ package deployer
 import (
 	"context"
 	"fmt"
-	"os"
 	"time"
 
 	"github.com/AlecAivazis/survey/v2"
@@ -11,10 +10,15 @@ import (
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
 	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
-	"github.com/briandowns/spinner"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/common-fate/clio"
 	"github.com/common-fate/cloudform/cfn"
 	"github.com/common-fate/cloudform/console"
+	"github.com/common-fate/cloudform/estimate"
+	"github.com/common-fate/cloudform/history"
+	"github.com/common-fate/cloudform/lock"
+	"github.com/common-fate/cloudform/policy"
+	"github.com/common-fate/cloudform/status"
 	"github.com/common-fate/cloudform/ui"
 	"github.com/pkg/errors"
 )
@@ -25,28 +29,120 @@ type Deployer struct {
 	cfnClient       *cloudformation.Client
 	cloudformClient *cfn.Cfn
 	uiClient        *ui.UI
+	term            *console.Terminal
+	stsClient       *sts.Client
+	durations       estimate.Store
+	slow            *ui.SlowResourceOpts
+	history         history.Store
+	historySet      bool
+	region          string
+	locker          lock.Locker
+	lockSet         bool
+	lockOpts        lock.AcquireOpts
+}
+
+// Option configures a Deployer
+type Option func(*Deployer)
+
+// WithTerminal sets the terminal that progress and prompts are written to.
+// The default is console.Default.
+func WithTerminal(t *console.Terminal) Option {
+	return func(b *Deployer) {
+		b.term = t
+	}
+}
+
+// WithDurationStore sets where the time taken by each resource is recorded,
+// which is used to estimate how long deployments have left.
+// The default is a file in the user's cache directory; see ui.WithDurationStore.
+func WithDurationStore(s estimate.Store) Option {
+	return func(b *Deployer) {
+		b.durations = s
+	}
+}
+
+// WithSlowResources sets how long resources can be in progress before they are reported as slow,
+// and a callback for each slow resource. See ui.WithSlowResources.
+func WithSlowResources(opts ui.SlowResourceOpts) Option {
+	return func(b *Deployer) {
+		b.slow = &opts
+	}
+}
+
+// WithHistory sets where a record of each Deploy and Delete is saved.
+// The default is a history.FileStore in the user's config directory; nil disables the history.
+func WithHistory(s history.Store) Option {
+	return func(b *Deployer) {
+		b.history = s
+		b.historySet = true
+	}
+}
+
+// WithLock sets the locker used to stop a stack being deployed or deleted by more than
+// one Deployer at a time, on this machine or others. The default is a lock file in the
+// user's cache directory, which only protects against deployments on the same machine;
+// use lock.NewDynamoLocker to share locks between machines. nil disables locking.
+func WithLock(l lock.Locker, opts lock.AcquireOpts) Option {
+	return func(b *Deployer) {
+		b.locker = l
+		b.lockSet = true
+		b.lockOpts = opts
+	}
 }
 
 // New creates a new
-func New(ctx context.Context) (*Deployer, error) {
+func New(ctx context.Context, opts ...Option) (*Deployer, error) {
 	cfg, err := config.LoadDefaultConfig(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return &Deployer{
-		cfnClient:       cloudformation.NewFromConfig(cfg),
-		cloudformClient: cfn.New(cfg),
-		uiClient:        ui.New(cfg),
-	}, nil
+	return NewFromConfig(cfg, opts...), nil
 }
 
 // NewFromConfig creates a Deployer from an existing AWS config.
-func NewFromConfig(cfg aws.Config) *Deployer {
-	return &Deployer{
+//
+// Unless opts say otherwise, a Deployer keeps state in the user's directories:
+// a record of each deployment in the config directory (see WithHistory), lock files
+// in the cache directory (see WithLock), and how long resources took to deploy in
+// the cache directory (see WithDurationStore). Pass WithHistory(nil), WithLock(nil, ...)
+// and WithDurationStore(estimate.NewMemoryStore()) to keep nothing on disk.
+func NewFromConfig(cfg aws.Config, opts ...Option) *Deployer {
+	b := &Deployer{
 		cfnClient:       cloudformation.NewFromConfig(cfg),
 		cloudformClient: cfn.New(cfg),
-		uiClient:        ui.New(cfg),
+		stsClient:       sts.NewFromConfig(cfg),
+		term:            console.Default,
+		region:          cfg.Region,
 	}
+
+	for _, o := range opts {
+		o(b)
+	}
+
+	if !b.historySet {
+		path, err := history.DefaultFileStorePath()
+		if err == nil {
+			b.history = history.NewFileStore(path)
+		}
+	}
+
+	if !b.lockSet {
+		dir, err := lock.DefaultFileLockerDir()
+		if err == nil {
+			b.locker = lock.NewFileLocker(dir)
+		}
+	}
+
+	uiOpts := []ui.Option{ui.WithTerminal(b.term)}
+	if b.durations != nil {
+		uiOpts = append(uiOpts, ui.WithDurationStore(b.durations))
+	}
+	if b.slow != nil {
+		uiOpts = append(uiOpts, ui.WithSlowResources(*b.slow))
+	}
+	b.uiClient = ui.New(cfg, uiOpts...)
+
+	return b
 }
 
 const noChangeFoundMsg = "The submitted information didn't contain changes. Submit different information to create a change set."
@@ -66,6 +162,57 @@ type DeployOpts struct {
 	// Confirm will skip interactive confirmations
 	// if set to tru
 	Confirm bool
+	// ChangeSetNameTemplate is an optional text/template used to
+	// name the change set. See cfn.ChangeSetNameData for the fields available.
+	ChangeSetNameTemplate string
+	// ChangeSetDescription is an optional description for the change set
+	ChangeSetDescription string
+	// Policy is an optional set of rules which the change set
+	// is checked against before it is executed
+	Policy *policy.Policy
+	// OverridePolicy allows overridable policy violations
+	// to proceed without an interactive confirmation
+	OverridePolicy bool
+	// StackPolicy is an optional stack policy body which
+	// is applied to the stack once the deployment has finished
+	StackPolicy string
+	// StackPolicyDuringUpdate is an optional stack policy body which temporarily
+	// replaces the stack's policy while an existing stack is being updated
+	StackPolicyDuringUpdate string
+	// TerminationProtection enables or disables termination protection
+	// once the deployment has finished. It is left unchanged if nil.
+	TerminationProtection *bool
+	// Dashboard shows progress in a full screen dashboard rather than
+	// inline, if the console is interactive
+	Dashboard bool
+	// WaitForCleanup waits for old resources to be removed after an update
+	// before returning. Otherwise Deploy returns as soon as the update has succeeded.
+	WaitForCleanup bool
+	// RollbackTriggers are the ARNs of CloudWatch alarms which roll the stack back
+	// if they go into alarm while it is being deployed or monitored
+	RollbackTriggers []string
+	// MonitoringPeriod is how long CloudFormation keeps watching the rollback triggers
+	// after the stack's resources have been deployed, rounded up to whole minutes.
+	// It can be at most three hours.
+	// If neither RollbackTriggers nor MonitoringPeriod is set, the stack keeps its existing configuration.
+	MonitoringPeriod time.Duration
+	// DisableRollback leaves the stack in CREATE_FAILED or UPDATE_FAILED if the deployment
+	// fails, keeping the resources which succeeded so that the template can be fixed and
+	// redeployed. Use RollbackStack to return the stack to its last stable state.
+	DisableRollback bool
+	// ReloadTemplate is called to read the fixed template when the user chooses to redeploy
+	// after a failure with DisableRollback set. If nil, Template is redeployed unchanged,
+	// which suits templates given as a URL.
+	ReloadTemplate func() (string, error)
+	// SkipValidation skips checking the template before the change set is created.
+	// Otherwise the template is checked locally and with CloudFormation's ValidateTemplate,
+	// and the deployment stops if any errors are found. ValidateTemplate needs the
+	// cloudformation:ValidateTemplate permission; without it, a warning is printed
+	// and only the local checks are used.
+	SkipValidation bool
+	// Hooks run at stages of the deployment, in order. See HookStage for when
+	// each stage runs and what happens when a hook fails.
+	Hooks []Hook
 }
 
 type DeployOptFunc func(*DeployOpts)
@@ -77,46 +224,169 @@ func WithConfirm(confirm bool) DeployOptFunc {
 	}
 }
 
+func (opts DeployOpts) requestOpts() []cfn.RequestOptFunc {
+	ro := make([]cfn.RequestOptFunc, 0)
+	if opts.ChangeSetNameTemplate != "" {
+		ro = append(ro, cfn.WithChangeSetNameTemplate(opts.ChangeSetNameTemplate))
+	}
+	if opts.ChangeSetDescription != "" {
+		ro = append(ro, cfn.WithChangeSetDescription(opts.ChangeSetDescription))
+	}
+	if rc := opts.rollbackConfiguration(); rc != nil {
+		ro = append(ro, cfn.WithRollbackConfiguration(rc))
+	}
+	if opts.DisableRollback {
+		ro = append(ro, cfn.WithDisableRollback(true))
+	}
+	return ro
+}
+
+// maxMonitoringPeriod is the longest monitoring period CloudFormation allows
+const maxMonitoringPeriod = 180 * time.Minute
+
+// rollbackConfiguration returns the rollback triggers and monitoring period for
+// the change set, or nil to keep the stack's existing configuration
+func (opts DeployOpts) rollbackConfiguration() *types.RollbackConfiguration {
+	if len(opts.RollbackTriggers) == 0 && opts.MonitoringPeriod == 0 {
+		return nil
+	}
+
+	rc := types.RollbackConfiguration{
+		RollbackTriggers: make([]types.RollbackTrigger, len(opts.RollbackTriggers)),
+	}
+	for i, arn := range opts.RollbackTriggers {
+		rc.RollbackTriggers[i] = types.RollbackTrigger{
+			Arn:  aws.String(arn),
+			Type: aws.String("AWS::CloudWatch::Alarm"),
+		}
+	}
+
+	if opts.MonitoringPeriod > 0 {
+		minutes := int32((opts.MonitoringPeriod + time.Minute - 1) / time.Minute)
+		rc.MonitoringTimeInMinutes = aws.Int32(minutes)
+	}
+
+	return &rc
+}
+
+// validateRollbackConfiguration returns an error if CloudFormation would reject the rollback configuration
+func (opts DeployOpts) validateRollbackConfiguration() error {
+	if opts.MonitoringPeriod < 0 || opts.MonitoringPeriod > maxMonitoringPeriod {
+		return errors.Errorf("monitoring period must be between 0 and %s, got %s", maxMonitoringPeriod, opts.MonitoringPeriod)
+	}
+	if len(opts.RollbackTriggers) > 5 {
+		return errors.Errorf("at most 5 rollback triggers can be set, got %d", len(opts.RollbackTriggers))
+	}
+	return nil
+}
+
 type DeployResult struct {
 	FinalStatus string
+	// Messages are the failure messages of the stack's resources
+	Messages []string
+	// FailedResources are the resources which failed when a deployment with
+	// DisableRollback set left the stack in CREATE_FAILED or UPDATE_FAILED
+	FailedResources []FailedResource
+	// RollbackTriggered is true if the stack was rolled back because
+	// one of opts.RollbackTriggers went into alarm
+	RollbackTriggered bool
+	// RolledBack is true if the stack was redeployed with its previous
+	// template because a post-execute hook failed
+	RolledBack bool
 }
 
+// ErrDeployFailed is returned along with the result of a deployment which
+// did not succeed, for example because the stack was rolled back
+var ErrDeployFailed = errors.New("deployment failed")
+
+// ErrRollbackTriggered is matched by the error returned when a deployment is rolled back
+// because one of its rollback triggers went into alarm. The error also wraps ErrDeployFailed.
+var ErrRollbackTriggered = errors.New("rollback triggered by alarm")
+
+// rollbackTriggeredError is a deployment failure caused by a rollback trigger
+type rollbackTriggeredError struct {
+	stackName string
+	status    string
+}
+
+func (e *rollbackTriggeredError) Error() string {
+	return fmt.Sprintf("%s: stack %s was rolled back by a rollback trigger and finished with status %s", ErrDeployFailed, e.stackName, e.status)
+}
+
+func (e *rollbackTriggeredError) Unwrap() error {
+	return ErrDeployFailed
+}
+
+func (e *rollbackTriggeredError) Is(target error) bool {
+	return target == ErrRollbackTriggered
+}
+
+// ErrDeployCancelled is returned by Deploy if the user doesn't approve the change set
+var ErrDeployCancelled = errors.New("user cancelled deployment")
+
 // Deploy deploys a stack and returns the final status
 // template can be either a URL or a template body
-func (b *Deployer) Deploy(ctx context.Context, opts DeployOpts) (*DeployResult, error) {
-	si := spinner.New(spinner.CharSets[14], 100*time.Millisecond)
-	si.Suffix = " creating CloudFormation change set"
-	si.Writer = os.Stderr
-	si.Start()
+//
+// ErrStackInProgress is returned if the stack is already being modified.
+// If the deployment fails, the result is returned along with an error wrapping ErrDeployFailed.
+// If opts.DisableRollback is set and the console is interactive, the user is offered a
+// chance to redeploy a fixed template or roll the stack back first; see DeployOpts.ReloadTemplate.
+// Each call is recorded in the deployment history; see WithHistory.
+func (b *Deployer) Deploy(ctx context.Context, opts DeployOpts) (res *DeployResult, err error) {
+	rec := b.newRecord(history.OperationDeploy, opts.StackName)
+	rec.TemplateHash = HashTemplate(opts.Template)
+	rec.Parameters = history.RedactParameters(opts.Params, opts.Template)
+
+	defer func() {
+		if res != nil {
+			b.saveRecord(rec, res.FinalStatus, res.Messages, err)
+		} else {
+			b.saveRecord(rec, "", nil, err)
+		}
+	}()
+
+	unlock, err := b.lockStack(ctx, opts.StackName)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
 
-	changeSetName, createErr := b.cloudformClient.CreateChangeSet(ctx, opts.Template, opts.Params, opts.Tags, opts.StackName, opts.RoleARN)
+	res, err = b.deploy(ctx, opts, rec)
+	if b.canRecover(res, err, opts) {
+		return b.recoverFailure(ctx, res, err, opts, rec)
+	}
 
-	si.Stop()
+	return res, err
+}
 
-	if createErr != nil {
-		if createErr.Error() == noChangeFoundMsg {
-			clio.Info("Skipped deployment (there are no changes in the changeset)")
+// deploy plans, confirms and applies a deployment. The caller must hold the stack's lock.
+func (b *Deployer) deploy(ctx context.Context, opts DeployOpts, rec *history.Record) (*DeployResult, error) {
+	err := b.checkNotInProgress(ctx, opts.StackName)
+	if err != nil {
+		return nil, err
+	}
 
-			res := DeployResult{
-				FinalStatus: "DEPLOY_SKIPPED",
-			}
+	plan, err := b.Plan(ctx, opts)
+	if err == ErrNoChanges {
+		clio.Info("Skipped deployment (there are no changes in the changeset)")
 
-			return &res, nil
+		res := DeployResult{
+			FinalStatus: "DEPLOY_SKIPPED",
 		}
 
-		return nil, errors.Wrap(createErr, "creating changeset")
-
+		return &res, nil
 	}
+	if err != nil {
+		return nil, err
+	}
+
+	recordChanges(rec, plan.ChangeSet)
 
 	confirm := opts.Confirm
 
 	if !confirm {
-		status, err := b.uiClient.FormatChangeSet(ctx, opts.StackName, changeSetName)
-		if err != nil {
-			return nil, err
-		}
 		clio.Info("The following CloudFormation changes will be made:")
-		fmt.Println(status)
+		b.term.Println(plan.Summary)
 
 		p := &survey.Confirm{Message: "Do you wish to continue?", Default: true}
 		err = survey.AskOne(p, &confirm)
@@ -124,31 +394,74 @@ func (b *Deployer) Deploy(ctx context.Context, opts DeployOpts) (*DeployResult,
 			return nil, err
 		}
 		if !confirm {
-			return nil, errors.New("user cancelled deployment")
+			return nil, ErrDeployCancelled
 		}
 	}
 
-	err := b.cloudformClient.ExecuteChangeSet(ctx, opts.StackName, changeSetName)
+	b.approve(ctx, rec, opts.Confirm)
+
+	return b.apply(ctx, plan, opts.applyOpts())
+}
+
+// waitForStack renders the stack's progress until it settles according to rules,
+// then prints and returns the result.
+// If dashboard is set, the full screen dashboard is used when the console is interactive.
+func (b *Deployer) waitForStack(ctx context.Context, stackName string, dashboard bool, rules status.SettleRules) (ui.WatchResult, error) {
+	if dashboard {
+		res, err := b.uiClient.RunDashboard(ctx, stackName, ui.DashboardOpts{KeepOpenOnFailure: true, Settle: rules})
+		if err != nil {
+			return res, err
+		}
+		b.printResult(res)
+		return res, nil
+	}
+
+	results, err := b.uiClient.WatchStacks(ctx, []string{stackName}, ui.WatchOpts{
+		OnSettle: b.printResult,
+		Settle:   rules,
+	})
 	if err != nil {
-		return nil, err
+		return ui.WatchResult{}, err
 	}
 
-	status, messages := b.uiClient.WaitForStackToSettle(ctx, opts.StackName)
+	return results[0], nil
+}
 
-	clio.Infof("Final stack status: %s", ui.ColouriseStatus(status))
+// outcomeError returns an error wrapping ErrDeployFailed or ErrDeleteFailed
+// if the operation in res did not succeed
+func outcomeError(res ui.WatchResult) error {
+	if res.Outcome != status.OutcomeFailure {
+		return nil
+	}
 
-	if len(messages) > 0 {
-		fmt.Println(console.Yellow("Messages:"))
-		for _, message := range messages {
-			fmt.Printf("  - %s\n", message)
-		}
+	if res.RollbackTriggered {
+		return &rollbackTriggeredError{stackName: res.StackName, status: res.Status}
 	}
 
-	res := DeployResult{
-		FinalStatus: status,
+	sentinel := ErrDeployFailed
+	if status.Stack(res.Status).Operation() == status.OperationDelete {
+		sentinel = ErrDeleteFailed
 	}
 
-	return &res, nil
+	return fmt.Errorf("%w: stack %s finished with status %s", sentinel, res.StackName, res.Status)
+}
+
+// printResult prints the final status of a stack operation
+func (b *Deployer) printResult(res ui.WatchResult) {
+	clio.Infof("Final stack status: %s", ui.ColouriseStatus(res.Status))
+	if res.RollbackTriggered {
+		clio.Warn("The stack was rolled back because a rollback trigger went into alarm")
+	}
+	b.printMessages(res)
+}
+
+func (b *Deployer) printMessages(res ui.WatchResult) {
+	if len(res.Messages) > 0 {
+		b.term.Println(console.Yellow("Messages:"))
+		for _, message := range res.Messages {
+			b.term.Printf("  - %s\n", message)
+		}
+	}
 }
 
 type DeleteOpts struct {
@@ -156,41 +469,127 @@ type DeleteOpts struct {
 	StackName string
 	// RoleARN is an optional deployment role to use
 	RoleARN string
+	// DisableTerminationProtection turns off termination protection
+	// without asking, if it is enabled on the stack
+	DisableTerminationProtection bool
+	// Confirm will skip the interactive confirmation
+	// of the resources to be deleted if set to true
+	Confirm bool
+	// RetainResources are the logical IDs of resources to keep.
+	// It can only be used when retrying a stack in the DELETE_FAILED state.
+	RetainResources []string
+	// PreDelete hooks are run after confirmation and before the stack is deleted.
+	// See EmptyS3Buckets and EmptyECRRepositories.
+	PreDelete []PreDeleteHook
+	// Timeout is an optional limit on how long the deletion may take,
+	// including waiting for the stack to be deleted
+	Timeout time.Duration
+	// Dashboard shows progress in a full screen dashboard rather than
+	// inline, if the console is interactive
+	Dashboard bool
 }
 
 type DeleteResult struct {
 	FinalStatus       string
 	DeleteStackOutput *cloudformation.DeleteStackOutput
+	// Messages are the failure messages of the stack's resources
+	Messages []string
 }
 
-// Delete a CloudFormation stack and returns the final status
-func (b *Deployer) Delete(ctx context.Context, opts DeleteOpts) (*DeleteResult, error) {
-	output, err := b.cloudformClient.DeleteStack(opts.StackName, opts.RoleARN)
+// ErrDeleteFailed is returned along with the result of a deletion which did not succeed
+var ErrDeleteFailed = errors.New("deletion failed")
+
+// ErrDeleteCancelled is returned by Delete if the user doesn't approve the deletion
+var ErrDeleteCancelled = errors.New("user cancelled deletion")
+
+// Delete a CloudFormation stack and returns the final status.
+// If the deletion fails, the result is returned along with an error wrapping ErrDeleteFailed.
+// Each call is recorded in the deployment history; see WithHistory.
+func (b *Deployer) Delete(ctx context.Context, opts DeleteOpts) (res *DeleteResult, err error) {
+	rec := b.newRecord(history.OperationDelete, opts.StackName)
+
+	defer func() {
+		if res != nil {
+			b.saveRecord(rec, res.FinalStatus, res.Messages, err)
+		} else {
+			b.saveRecord(rec, "", nil, err)
+		}
+	}()
+
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	unlock, err := b.lockStack(ctx, opts.StackName)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	stack, err := b.cloudformClient.GetStack(ctx, opts.StackName)
+	if err != nil {
+		return nil, err
+	}
+
+	// A previous deletion was interrupted, so wait for it rather than starting again
+	if stack.StackStatus == types.StackStatusDeleteInProgress {
+		res, err := b.Resume(ctx, opts.StackName)
+		if res == nil {
+			return nil, err
+		}
+		return &DeleteResult{FinalStatus: res.FinalStatus, Messages: res.Messages}, err
+	}
+
+	err = b.checkTerminationProtection(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	si := spinner.New(spinner.CharSets[14], 100*time.Millisecond)
-	si.Suffix = " creating CloudFormation change set"
-	si.Writer = os.Stderr
-	si.Start()
-	si.Stop()
+	resources, err := b.DeletePreview(ctx, opts.StackName, opts.RetainResources...)
+	if err != nil {
+		return nil, err
+	}
 
-	status, messages := b.uiClient.WaitForStackToSettle(ctx, opts.StackName)
+	confirm := opts.Confirm
 
-	clio.Infof("Final stack status: %s", ui.ColouriseStatus(status))
+	if !confirm {
+		clio.Infof("The following resources will be deleted from stack %s:", opts.StackName)
+		b.term.Println(FormatDeletePreview(resources))
 
-	if len(messages) > 0 {
-		fmt.Println(console.Yellow("Messages:"))
-		for _, message := range messages {
-			fmt.Printf("  - %s\n", message)
+		p := &survey.Confirm{Message: "Do you wish to continue?", Default: false}
+		err = survey.AskOne(p, &confirm)
+		if err != nil {
+			return nil, err
+		}
+		if !confirm {
+			return nil, ErrDeleteCancelled
 		}
 	}
 
-	res := DeleteResult{
-		FinalStatus:       status,
-		DeleteStackOutput: output,
+	b.approve(ctx, rec, opts.Confirm)
+
+	for _, hook := range opts.PreDelete {
+		err = hook(ctx, resources)
+		if err != nil {
+			return nil, errors.Wrap(err, "running pre-delete hook")
+		}
 	}
 
-	return &res, nil
+	output, err := b.cloudformClient.DeleteStack(ctx, opts.StackName, opts.RoleARN, cfn.WithRetainResources(opts.RetainResources...), cfn.WithClientRequestToken(deleteToken(stack, opts.RetainResources)))
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := b.waitForStack(ctx, opts.StackName, opts.Dashboard, status.SettleRules{Operation: status.OperationDelete})
+	if err != nil {
+		return nil, errors.Wrapf(err, "waiting for %s to be deleted", opts.StackName)
+	}
+
+	return &DeleteResult{
+		FinalStatus:       result.Status,
+		DeleteStackOutput: output,
+		Messages:          result.Messages,
+	}, outcomeError(result)
 }
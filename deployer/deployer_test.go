@@ -0,0 +1,229 @@
+package deployer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/smithy-go/ptr"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/common-fate/cloudform/history"
+	"github.com/common-fate/cloudform/status"
+	"github.com/common-fate/cloudform/ui"
+)
+
+func TestOutcomeError(t *testing.T) {
+	for _, tc := range []struct {
+		result   ui.WatchResult
+		expected error
+	}{
+		{ui.WatchResult{Status: "UPDATE_COMPLETE", Outcome: status.OutcomeSuccess}, nil},
+		{ui.WatchResult{Status: "ROLLBACK_COMPLETE", Outcome: status.OutcomeFailure}, ErrDeployFailed},
+		{ui.WatchResult{Status: "DELETE_FAILED", Outcome: status.OutcomeFailure}, ErrDeleteFailed},
+	} {
+		err := outcomeError(tc.result)
+		if !errors.Is(err, tc.expected) {
+			t.Errorf("%s: expected %v, got %v", tc.result.Status, tc.expected, err)
+		}
+	}
+}
+
+func TestPlanSettleRules(t *testing.T) {
+	p := &Plan{StackStatus: "REVIEW_IN_PROGRESS"}
+	if op := p.settleRules(ApplyOpts{}).Operation; op != status.OperationCreate {
+		t.Errorf("expected a new stack to be created, got %s", op)
+	}
+
+	p = &Plan{StackStatus: "UPDATE_COMPLETE"}
+	rules := p.settleRules(ApplyOpts{WaitForCleanup: true})
+	if rules.Operation != status.OperationUpdate || !rules.WaitForCleanup {
+		t.Errorf("unexpected rules %+v", rules)
+	}
+}
+
+func TestCheckPlan(t *testing.T) {
+	const template = "Resources:\n  Queue:\n    Type: AWS::SQS::Queue\n"
+	const changeSetID = "arn:aws:cloudformation:us-east-1:123456789012:changeSet/app-1/1"
+
+	for _, tc := range []struct {
+		name     string
+		status   types.StackStatus
+		template string
+		err      string
+	}{
+		{name: "unchanged", status: types.StackStatusUpdateComplete, template: template},
+		{name: "different template", status: types.StackStatusUpdateComplete, template: "Resources: {}", err: "doesn't have the template the plan was made with"},
+		{name: "stack changed", status: types.StackStatusUpdateRollbackComplete, template: template, err: "has changed since the plan was made"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fake := &fakeCloudFormation{
+				stacks: map[string]types.Stack{"app": fakeStack("app", tc.status)},
+				changeSets: map[string]cloudformation.DescribeChangeSetOutput{
+					changeSetID: {ExecutionStatus: types.ExecutionStatusAvailable},
+				},
+				templates: map[string]string{changeSetID: tc.template},
+			}
+
+			plan := &Plan{
+				StackName:     "app",
+				ChangeSetName: "app-1",
+				ChangeSetID:   changeSetID,
+				TemplateHash:  HashTemplate(template),
+				StackStatus:   "UPDATE_COMPLETE",
+			}
+
+			checkError(t, fake.deployer().checkPlan(context.Background(), plan), tc.err)
+		})
+	}
+}
+
+func TestCreateChangeSetDisableRollback(t *testing.T) {
+	for _, tc := range []struct {
+		name            string
+		stacks          map[string]types.Stack
+		disableRollback bool
+		expected        types.OnStackFailure
+	}{
+		{name: "new stack", disableRollback: true, expected: types.OnStackFailureDoNothing},
+		{name: "new stack with rollback"},
+		// Existing stacks have rollback disabled when the change set is executed
+		{name: "existing stack", stacks: map[string]types.Stack{"app": fakeStack("app", types.StackStatusUpdateComplete)}, disableRollback: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fake := &fakeCloudFormation{stacks: tc.stacks}
+			opts := DeployOpts{StackName: "app", Template: "Resources: {}", DisableRollback: tc.disableRollback}
+
+			_, err := fake.deployer().cloudformClient.CreateChangeSet(context.Background(), opts.Template, nil, nil, opts.StackName, "", opts.requestOpts()...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(fake.created) != 1 {
+				t.Fatalf("expected one change set to be created, got %d", len(fake.created))
+			}
+			if got := fake.created[0].OnStackFailure; got != tc.expected {
+				t.Errorf("expected OnStackFailure %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestRecordResult(t *testing.T) {
+	for _, tc := range []struct {
+		finalStatus string
+		err         error
+		expected    history.Result
+	}{
+		{"UPDATE_COMPLETE", nil, history.ResultSucceeded},
+		{"DEPLOY_SKIPPED", nil, history.ResultSkipped},
+		{"ROLLBACK_COMPLETE", fmt.Errorf("%w: stack app finished with status ROLLBACK_COMPLETE", ErrDeployFailed), history.ResultFailed},
+		{"", ErrDeleteCancelled, history.ResultCancelled},
+		{"UPDATE_COMPLETE", hookError(HookPostExecute, []hookFailure{{hook: Hook{Name: "smoke test"}, err: errors.New("exit status 1")}}), history.ResultFailed},
+		{"", errors.New("creating changeset: template is invalid"), history.ResultError},
+	} {
+		if actual := recordResult(tc.finalStatus, tc.err); actual != tc.expected {
+			t.Errorf("%s %v: expected %s, got %s", tc.finalStatus, tc.err, tc.expected, actual)
+		}
+	}
+}
+
+func TestRecordChanges(t *testing.T) {
+	rec := history.NewRecord(history.OperationDeploy, "app", time.Now())
+
+	recordChanges(&rec, &ui.ChangeSet{
+		StackID:       "arn:aws:cloudformation:us-east-1:123456789012:stack/app/abc",
+		ChangeSetName: "cloudform-1",
+		Changes: []ui.ResourceChange{
+			{Action: "Modify", LogicalResourceID: "Function", ResourceType: "AWS::Lambda::Function", Replacement: "False"},
+			{Action: "Modify", LogicalResourceID: "Database", ResourceType: "AWS::CloudFormation::Stack", Nested: &ui.ChangeSet{
+				Changes: []ui.ResourceChange{
+					{Action: "Add", LogicalResourceID: "Table", ResourceType: "AWS::DynamoDB::Table"},
+				},
+			}},
+		},
+	})
+
+	expected := []history.Change{
+		{Action: "Modify", LogicalID: "Function", ResourceType: "AWS::Lambda::Function", Replacement: "False"},
+		{Action: "Modify", LogicalID: "Database", ResourceType: "AWS::CloudFormation::Stack"},
+		{Action: "Add", LogicalID: "Database/Table", ResourceType: "AWS::DynamoDB::Table"},
+	}
+
+	if diff := cmp.Diff(expected, rec.Changes); diff != "" {
+		t.Error(diff)
+	}
+	if rec.ChangeSetName != "cloudform-1" || rec.StackID == "" {
+		t.Errorf("unexpected record %+v", rec)
+	}
+}
+
+func TestRollbackConfiguration(t *testing.T) {
+	if rc := (DeployOpts{}).rollbackConfiguration(); rc != nil {
+		t.Errorf("expected the existing configuration to be kept, got %+v", rc)
+	}
+
+	opts := DeployOpts{
+		RollbackTriggers: []string{"arn:aws:cloudwatch:us-east-1:123456789012:alarm:api-errors"},
+		MonitoringPeriod: 90 * time.Second,
+	}
+	rc := opts.rollbackConfiguration()
+	if len(rc.RollbackTriggers) != 1 || ptr.ToString(rc.RollbackTriggers[0].Type) != "AWS::CloudWatch::Alarm" {
+		t.Errorf("unexpected triggers %+v", rc.RollbackTriggers)
+	}
+	if minutes := ptr.ToInt32(rc.MonitoringTimeInMinutes); minutes != 2 {
+		t.Errorf("expected the monitoring period to be rounded up to 2 minutes, got %d", minutes)
+	}
+
+	opts.MonitoringPeriod = 4 * time.Hour
+	if err := opts.validateRollbackConfiguration(); err == nil {
+		t.Error("expected a monitoring period over three hours to be rejected")
+	}
+}
+
+func TestOutcomeErrorRollbackTriggered(t *testing.T) {
+	err := outcomeError(ui.WatchResult{StackName: "app", Status: "UPDATE_ROLLBACK_COMPLETE", Outcome: status.OutcomeFailure, RollbackTriggered: true})
+	if !errors.Is(err, ErrRollbackTriggered) || !errors.Is(err, ErrDeployFailed) {
+		t.Errorf("expected a triggered rollback, got %v", err)
+	}
+
+	err = outcomeError(ui.WatchResult{StackName: "app", Status: "UPDATE_ROLLBACK_COMPLETE", Outcome: status.OutcomeFailure})
+	if errors.Is(err, ErrRollbackTriggered) || !errors.Is(err, ErrDeployFailed) {
+		t.Errorf("expected a plain failure, got %v", err)
+	}
+}
+
+func TestLeftFailed(t *testing.T) {
+	for status, expected := range map[string]bool{
+		"CREATE_FAILED":            true,
+		"UPDATE_FAILED":            true,
+		"UPDATE_ROLLBACK_FAILED":   false,
+		"ROLLBACK_COMPLETE":        false,
+		"UPDATE_ROLLBACK_COMPLETE": false,
+		"DELETE_FAILED":            false,
+		"UPDATE_COMPLETE":          false,
+	} {
+		if actual := leftFailed(status); actual != expected {
+			t.Errorf("%s: expected %t, got %t", status, expected, actual)
+		}
+	}
+}
+
+func TestExecuteToken(t *testing.T) {
+	a := executeToken("arn:aws:cloudformation:us-east-1:123456789012:changeSet/cloudform-1/aaaa")
+	b := executeToken("arn:aws:cloudformation:us-east-1:123456789012:changeSet/cloudform-1/bbbb")
+
+	if a == b {
+		t.Error("expected change sets with the same name to get different tokens")
+	}
+	if a != executeToken("arn:aws:cloudformation:us-east-1:123456789012:changeSet/cloudform-1/aaaa") {
+		t.Error("expected the token to be the same for the same change set")
+	}
+	if len(a) > maxTokenLength {
+		t.Errorf("expected the token to be at most %d characters, got %d", maxTokenLength, len(a))
+	}
+}
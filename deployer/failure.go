@@ -0,0 +1,227 @@
+package deployer
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/aws/smithy-go/ptr"
+	"github.com/common-fate/clio"
+	"github.com/common-fate/cloudform/console"
+	"github.com/common-fate/cloudform/history"
+	"github.com/common-fate/cloudform/status"
+	"github.com/pkg/errors"
+)
+
+// FailedResource is a resource which failed to change during a deployment
+type FailedResource struct {
+	// LogicalID is prefixed with the logical IDs of any nested stacks, such as "Database/Table"
+	LogicalID    string
+	PhysicalID   string
+	ResourceType string
+	Status       string
+	Reason       string
+}
+
+// leftFailed returns true if a stack was left in a failed state rather than being
+// rolled back, which happens when rollback is disabled
+func leftFailed(stackStatus string) bool {
+	s := status.Stack(stackStatus)
+	return s.IsFailureStatus() && !s.IsRollback() && s.Operation() != status.OperationDelete
+}
+
+// failedResources returns the failed resources of a stack. Failures in nested stacks
+// are listed in place of the nested stack resource. Stacks which can't be listed
+// are skipped, as the failure messages are reported either way.
+func (b *Deployer) failedResources(ctx context.Context, stackName string) []FailedResource {
+	return b.collectFailedResources(ctx, stackName, "")
+}
+
+func (b *Deployer) collectFailedResources(ctx context.Context, stackName, prefix string) []FailedResource {
+	resources, err := b.cloudformClient.GetStackResources(ctx, stackName)
+	if err != nil {
+		clio.Debugf("listing resources of %s: %s", stackName, err)
+		return nil
+	}
+
+	var failed []FailedResource
+	for _, r := range resources {
+		if !status.Resource(r.ResourceStatus).IsFailure() {
+			continue
+		}
+
+		logicalID := prefix + ptr.ToString(r.LogicalResourceId)
+		physicalID := ptr.ToString(r.PhysicalResourceId)
+
+		if ptr.ToString(r.ResourceType) == "AWS::CloudFormation::Stack" && physicalID != "" {
+			nested := b.collectFailedResources(ctx, physicalID, logicalID+"/")
+			if len(nested) > 0 {
+				failed = append(failed, nested...)
+				continue
+			}
+		}
+
+		failed = append(failed, FailedResource{
+			LogicalID:    logicalID,
+			PhysicalID:   physicalID,
+			ResourceType: ptr.ToString(r.ResourceType),
+			Status:       string(r.ResourceStatus),
+			Reason:       ptr.ToString(r.ResourceStatusReason),
+		})
+	}
+
+	return failed
+}
+
+// RollbackStack rolls back a stack which a deployment with DisableRollback set
+// left in CREATE_FAILED or UPDATE_FAILED, returning it to its last stable state.
+// If the rollback doesn't succeed, the result is returned along with an error wrapping ErrDeployFailed.
+// The stack is locked while it is rolled back; see WithLock.
+func (b *Deployer) RollbackStack(ctx context.Context, stackName string, opts DeployOpts) (*DeployResult, error) {
+	unlock, err := b.lockStack(ctx, stackName)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	return b.rollbackStack(ctx, stackName, opts)
+}
+
+// rollbackStack is RollbackStack for callers which already hold the stack's lock
+func (b *Deployer) rollbackStack(ctx context.Context, stackName string, opts DeployOpts) (*DeployResult, error) {
+	err := b.cloudformClient.RollbackStack(ctx, stackName, opts.RoleARN)
+	if err != nil {
+		return nil, errors.Wrapf(err, "rolling back stack %s", stackName)
+	}
+
+	result, err := b.waitForStack(ctx, stackName, opts.Dashboard, status.SettleRules{Operation: status.OperationRollback})
+	if err != nil {
+		return nil, err
+	}
+
+	res := DeployResult{
+		FinalStatus: result.Status,
+		Messages:    result.Messages,
+	}
+
+	if result.Outcome != status.OutcomeSuccess {
+		return &res, fmt.Errorf("%w: rolling back stack %s finished with status %s", ErrDeployFailed, stackName, result.Status)
+	}
+
+	return &res, nil
+}
+
+// canRecover returns true if the user can be asked what to do about a
+// deployment which failed with rollback disabled
+func (b *Deployer) canRecover(res *DeployResult, err error, opts DeployOpts) bool {
+	return opts.DisableRollback && !opts.Confirm && b.term.IsInteractive() &&
+		res != nil && leftFailed(res.FinalStatus) && stderrors.Is(err, ErrDeployFailed)
+}
+
+const (
+	recoverRedeploy = "Fix the template and redeploy"
+	recoverRollback = "Roll back now"
+	recoverLeave    = "Leave the stack as it is"
+)
+
+// recoverFailure asks the user whether to redeploy, roll back or leave a stack which a
+// deployment with rollback disabled left failed, until the stack is deployed or the user stops.
+// The caller must hold the stack's lock.
+func (b *Deployer) recoverFailure(ctx context.Context, res *DeployResult, deployErr error, opts DeployOpts, rec *history.Record) (*DeployResult, error) {
+	for {
+		printFailedResources(res.FailedResources)
+
+		var choice string
+		p := &survey.Select{
+			Message: fmt.Sprintf("Stack %s is %s. What would you like to do?", opts.StackName, res.FinalStatus),
+			Options: []string{recoverRedeploy, recoverRollback, recoverLeave},
+			Default: recoverRedeploy,
+		}
+		err := survey.AskOne(p, &choice)
+		if err != nil {
+			return res, deployErr
+		}
+
+		switch choice {
+		case recoverRedeploy:
+			retry, err := b.redeploy(ctx, opts, rec)
+			if stderrors.Is(err, ErrDeployCancelled) {
+				continue
+			}
+			if retry == nil {
+				// The redeploy didn't start, for example because the template is invalid
+				clio.Errorf("Redeploying %s: %s", opts.StackName, err)
+				continue
+			}
+			if retry.FinalStatus == "DEPLOY_SKIPPED" {
+				clio.Warn("The template and parameters haven't changed since the failed deployment")
+				continue
+			}
+
+			res, deployErr = retry, err
+			if !leftFailed(res.FinalStatus) {
+				return res, deployErr
+			}
+
+		case recoverRollback:
+			return b.rollbackFailed(ctx, res, deployErr, opts)
+
+		default:
+			clio.Infof("Leaving %s as %s. Redeploy to fix it, or use RollbackStack to roll it back.", opts.StackName, res.FinalStatus)
+			return res, deployErr
+		}
+	}
+}
+
+// rollbackFailed rolls back a stack which a deployment left failed. If the rollback succeeds,
+// the result keeps the failed deployment's messages and failed resources, and its error,
+// as they explain why the stack was rolled back.
+func (b *Deployer) rollbackFailed(ctx context.Context, res *DeployResult, deployErr error, opts DeployOpts) (*DeployResult, error) {
+	rolledBack, err := b.rollbackStack(ctx, opts.StackName, opts)
+	if err != nil {
+		return rolledBack, err
+	}
+
+	rolledBack.Messages = res.Messages
+	rolledBack.FailedResources = res.FailedResources
+
+	return rolledBack, deployErr
+}
+
+// redeploy reloads the template if possible and deploys it again
+func (b *Deployer) redeploy(ctx context.Context, opts DeployOpts, rec *history.Record) (*DeployResult, error) {
+	if opts.ReloadTemplate != nil {
+		ready := true
+		p := &survey.Confirm{Message: "Fix the template, then continue to redeploy it. Continue?", Default: true}
+		err := survey.AskOne(p, &ready)
+		if err != nil {
+			return nil, err
+		}
+		if !ready {
+			return nil, ErrDeployCancelled
+		}
+
+		opts.Template, err = opts.ReloadTemplate()
+		if err != nil {
+			return nil, errors.Wrap(err, "reloading template")
+		}
+	}
+
+	rec.TemplateHash = HashTemplate(opts.Template)
+	rec.Parameters = history.RedactParameters(opts.Params, opts.Template)
+
+	return b.deploy(ctx, opts, rec)
+}
+
+// printFailedResources lists the resources which failed
+func printFailedResources(resources []FailedResource) {
+	if len(resources) == 0 {
+		return
+	}
+
+	clio.Error("The following resources failed:")
+	for _, r := range resources {
+		clio.Logf("  - %s %s: %s", console.Yellow(r.LogicalID), console.Grey(r.ResourceType), r.Reason)
+	}
+}
@@ -0,0 +1,247 @@
+package deployer
+
+import (
+	"bytes"
+	"context"
+	stderrors "errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/aws/smithy-go/ptr"
+	"github.com/common-fate/cloudform/console"
+	"github.com/common-fate/cloudform/estimate"
+	"github.com/common-fate/cloudform/lock"
+	"github.com/google/go-cmp/cmp"
+)
+
+// fakeCloudFormation answers CloudFormation calls from its stacks and resources
+// instead of sending requests. Stacks are looked up by name or ID.
+type fakeCloudFormation struct {
+	mu        sync.Mutex
+	stacks    map[string]types.Stack
+	resources map[string][]types.StackResource
+	// afterRollback is the status stacks are given when they are rolled back
+	afterRollback types.StackStatus
+	rolledBack    []string
+	// validateErr is returned by ValidateTemplate
+	validateErr error
+	// changeSets and templates are looked up by change set ID or name
+	changeSets map[string]cloudformation.DescribeChangeSetOutput
+	templates  map[string]string
+	// created holds the input of each CreateChangeSet call
+	created []cloudformation.CreateChangeSetInput
+}
+
+// deployer returns a Deployer which uses the fake, writing its output to a buffer
+// and keeping nothing in the user's directories
+func (f *fakeCloudFormation) deployer() *Deployer {
+	cfg := aws.Config{
+		Region:      "us-east-1",
+		Credentials: aws.AnonymousCredentials{},
+		APIOptions: []func(*middleware.Stack) error{
+			func(s *middleware.Stack) error {
+				return s.Initialize.Add(middleware.InitializeMiddlewareFunc("fakeCloudFormation", f.handle), middleware.Before)
+			},
+		},
+	}
+
+	return NewFromConfig(cfg,
+		WithTerminal(console.NewTerminal(&bytes.Buffer{})),
+		WithHistory(nil),
+		WithLock(nil, lock.AcquireOpts{}),
+		WithDurationStore(estimate.NewMemoryStore()),
+	)
+}
+
+func (f *fakeCloudFormation) handle(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var out interface{}
+	var err error
+
+	switch params := in.Parameters.(type) {
+	case *cloudformation.DescribeStacksInput:
+		stack, ok := f.stack(ptr.ToString(params.StackName))
+		if !ok {
+			err = &smithy.GenericAPIError{Code: "ValidationError", Message: "Stack does not exist"}
+			break
+		}
+		out = &cloudformation.DescribeStacksOutput{Stacks: []types.Stack{stack}}
+
+	case *cloudformation.DescribeStackResourcesInput:
+		stack, ok := f.stack(ptr.ToString(params.StackName))
+		if !ok {
+			err = &smithy.GenericAPIError{Code: "ValidationError", Message: "Stack does not exist"}
+			break
+		}
+		out = &cloudformation.DescribeStackResourcesOutput{StackResources: f.resources[ptr.ToString(stack.StackName)]}
+
+	case *cloudformation.ValidateTemplateInput:
+		out, err = &cloudformation.ValidateTemplateOutput{}, f.validateErr
+
+	case *cloudformation.DescribeChangeSetInput:
+		changeSet, ok := f.changeSets[ptr.ToString(params.ChangeSetName)]
+		if !ok {
+			err = &smithy.GenericAPIError{Code: "ChangeSetNotFound", Message: "Change set does not exist"}
+			break
+		}
+		out = &changeSet
+
+	case *cloudformation.CreateChangeSetInput:
+		f.created = append(f.created, *params)
+		if f.changeSets == nil {
+			f.changeSets = make(map[string]cloudformation.DescribeChangeSetOutput)
+		}
+		f.changeSets[ptr.ToString(params.ChangeSetName)] = cloudformation.DescribeChangeSetOutput{Status: types.ChangeSetStatusCreateComplete}
+		out = &cloudformation.CreateChangeSetOutput{}
+
+	case *cloudformation.GetTemplateInput:
+		template, ok := f.templates[ptr.ToString(params.ChangeSetName)]
+		if !ok {
+			err = &smithy.GenericAPIError{Code: "ChangeSetNotFound", Message: "Change set does not exist"}
+			break
+		}
+		out = &cloudformation.GetTemplateOutput{TemplateBody: ptr.String(template)}
+
+	case *cloudformation.RollbackStackInput:
+		stack, ok := f.stack(ptr.ToString(params.StackName))
+		if !ok {
+			err = &smithy.GenericAPIError{Code: "ValidationError", Message: "Stack does not exist"}
+			break
+		}
+		stack.StackStatus = f.afterRollback
+		f.stacks[ptr.ToString(stack.StackName)] = stack
+		f.rolledBack = append(f.rolledBack, ptr.ToString(stack.StackName))
+		out = &cloudformation.RollbackStackOutput{StackId: stack.StackId}
+
+	default:
+		err = fmt.Errorf("unexpected call %T", params)
+	}
+
+	return middleware.InitializeOutput{Result: out}, middleware.Metadata{}, err
+}
+
+func (f *fakeCloudFormation) stack(nameOrID string) (types.Stack, bool) {
+	for name, stack := range f.stacks {
+		if name == nameOrID || ptr.ToString(stack.StackId) == nameOrID {
+			return stack, true
+		}
+	}
+	return types.Stack{}, false
+}
+
+func fakeStack(name string, status types.StackStatus) types.Stack {
+	return types.Stack{
+		StackName:   ptr.String(name),
+		StackId:     ptr.String("arn:aws:cloudformation:us-east-1:123456789012:stack/" + name + "/1"),
+		StackStatus: status,
+	}
+}
+
+func fakeResource(logicalID, resourceType string, status types.ResourceStatus, physicalID, reason string) types.StackResource {
+	r := types.StackResource{
+		LogicalResourceId: ptr.String(logicalID),
+		ResourceType:      ptr.String(resourceType),
+		ResourceStatus:    status,
+	}
+	if physicalID != "" {
+		r.PhysicalResourceId = ptr.String(physicalID)
+	}
+	if reason != "" {
+		r.ResourceStatusReason = ptr.String(reason)
+	}
+	return r
+}
+
+func TestFailedResources(t *testing.T) {
+	database := fakeStack("app-Database", types.StackStatusCreateFailed)
+	fake := &fakeCloudFormation{
+		stacks: map[string]types.Stack{
+			"app":          fakeStack("app", types.StackStatusCreateFailed),
+			"app-Database": database,
+		},
+		resources: map[string][]types.StackResource{
+			"app": {
+				fakeResource("Bucket", "AWS::S3::Bucket", types.ResourceStatusCreateComplete, "app-bucket", ""),
+				fakeResource("Role", "AWS::IAM::Role", types.ResourceStatusCreateFailed, "", "Access denied"),
+				fakeResource("Database", "AWS::CloudFormation::Stack", types.ResourceStatusCreateFailed, ptr.ToString(database.StackId), "Embedded stack failed"),
+				// Nested stacks which can't be listed are reported themselves
+				fakeResource("Cache", "AWS::CloudFormation::Stack", types.ResourceStatusCreateFailed, "arn:aws:cloudformation:us-east-1:123456789012:stack/app-Cache/1", "Embedded stack failed"),
+			},
+			"app-Database": {
+				fakeResource("Table", "AWS::DynamoDB::Table", types.ResourceStatusCreateFailed, "app-table", "Table already exists"),
+				fakeResource("Key", "AWS::KMS::Key", types.ResourceStatusCreateComplete, "key", ""),
+			},
+		},
+	}
+
+	actual := fake.deployer().failedResources(context.Background(), "app")
+	expected := []FailedResource{
+		{LogicalID: "Role", ResourceType: "AWS::IAM::Role", Status: "CREATE_FAILED", Reason: "Access denied"},
+		{LogicalID: "Database/Table", PhysicalID: "app-table", ResourceType: "AWS::DynamoDB::Table", Status: "CREATE_FAILED", Reason: "Table already exists"},
+		{LogicalID: "Cache", PhysicalID: "arn:aws:cloudformation:us-east-1:123456789012:stack/app-Cache/1", ResourceType: "AWS::CloudFormation::Stack", Status: "CREATE_FAILED", Reason: "Embedded stack failed"},
+	}
+
+	if d := cmp.Diff(expected, actual); d != "" {
+		t.Error(d)
+	}
+}
+
+func TestRollbackFailed(t *testing.T) {
+	deployErr := fmt.Errorf("%w: stack app finished with status UPDATE_FAILED", ErrDeployFailed)
+	failed := &DeployResult{
+		FinalStatus:     "UPDATE_FAILED",
+		Messages:        []string{"Role: Access denied"},
+		FailedResources: []FailedResource{{LogicalID: "Role", Status: "UPDATE_FAILED", Reason: "Access denied"}},
+	}
+
+	for _, tc := range []struct {
+		name          string
+		afterRollback types.StackStatus
+		keepFailures  bool
+	}{
+		{name: "rolled back", afterRollback: types.StackStatusUpdateRollbackComplete, keepFailures: true},
+		{name: "rollback failed", afterRollback: types.StackStatusUpdateRollbackFailed},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fake := &fakeCloudFormation{
+				stacks:        map[string]types.Stack{"app": fakeStack("app", types.StackStatusUpdateFailed)},
+				afterRollback: tc.afterRollback,
+			}
+
+			res, err := fake.deployer().rollbackFailed(context.Background(), failed, deployErr, DeployOpts{StackName: "app"})
+
+			if !stderrors.Is(err, ErrDeployFailed) {
+				t.Errorf("expected an error wrapping ErrDeployFailed, got %v", err)
+			}
+			if d := cmp.Diff([]string{"app"}, fake.rolledBack); d != "" {
+				t.Error(d)
+			}
+			if res == nil {
+				t.Fatal("expected a result")
+			}
+			if res.FinalStatus != string(tc.afterRollback) {
+				t.Errorf("expected status %s, got %s", tc.afterRollback, res.FinalStatus)
+			}
+
+			if tc.keepFailures {
+				if err != deployErr {
+					t.Errorf("expected the deployment's error, got %v", err)
+				}
+				if d := cmp.Diff(failed.Messages, res.Messages); d != "" {
+					t.Error(d)
+				}
+				if d := cmp.Diff(failed.FailedResources, res.FailedResources); d != "" {
+					t.Error(d)
+				}
+			}
+		})
+	}
+}
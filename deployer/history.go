@@ -0,0 +1,121 @@
+package deployer
+
+import (
+	"context"
+	"errors"
+	"os/user"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go/ptr"
+	"github.com/common-fate/clio"
+	"github.com/common-fate/cloudform/history"
+	"github.com/common-fate/cloudform/ui"
+	"github.com/gookit/color"
+)
+
+// newRecord starts a history record for a deploy or delete
+func (b *Deployer) newRecord(op history.Operation, stackName string) *history.Record {
+	rec := history.NewRecord(op, stackName, time.Now())
+	return &rec
+}
+
+// approve records who approved the change in rec.
+// Errors finding the identity are ignored, leaving the field empty.
+func (b *Deployer) approve(ctx context.Context, rec *history.Record, autoApproved bool) {
+	rec.Approver.AutoApproved = autoApproved
+
+	if u, err := user.Current(); err == nil {
+		rec.Approver.User = u.Username
+	}
+
+	if b.stsClient != nil {
+		identity, err := b.stsClient.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+		if err == nil {
+			rec.Approver.AWSIdentity = ptr.ToString(identity.Arn)
+		}
+	}
+}
+
+// recordChanges adds a summary of a change set to rec
+func recordChanges(rec *history.Record, cs *ui.ChangeSet) {
+	if cs == nil {
+		return
+	}
+
+	rec.StackID = cs.StackID
+	rec.ChangeSetName = cs.ChangeSetName
+
+	var add func(cs *ui.ChangeSet, prefix string)
+	add = func(cs *ui.ChangeSet, prefix string) {
+		for _, c := range cs.Changes {
+			rec.Changes = append(rec.Changes, history.Change{
+				Action:       c.Action,
+				LogicalID:    prefix + c.LogicalResourceID,
+				ResourceType: c.ResourceType,
+				Replacement:  c.Replacement,
+			})
+			if c.Nested != nil {
+				add(c.Nested, prefix+c.LogicalResourceID+"/")
+			}
+		}
+	}
+	add(cs, "")
+}
+
+// recordResult decides how a run finished from the error it returned
+func recordResult(finalStatus string, err error) history.Result {
+	switch {
+	case err == nil && finalStatus == "DEPLOY_SKIPPED":
+		return history.ResultSkipped
+	case err == nil:
+		return history.ResultSucceeded
+	case errors.Is(err, ErrDeployFailed), errors.Is(err, ErrDeleteFailed), errors.Is(err, ErrHookFailed):
+		return history.ResultFailed
+	case errors.Is(err, ErrDeployCancelled), errors.Is(err, ErrDeleteCancelled):
+		return history.ResultCancelled
+	default:
+		return history.ResultError
+	}
+}
+
+// saveRecord finishes rec and saves it to the history store.
+// A failure to save is reported as a warning rather than failing the run.
+func (b *Deployer) saveRecord(rec *history.Record, finalStatus string, messages []string, err error) {
+	if b.history == nil {
+		return
+	}
+
+	rec.FinishedAt = time.Now()
+	rec.Duration = rec.FinishedAt.Sub(rec.StartedAt)
+	rec.FinalStatus = finalStatus
+	rec.Result = recordResult(finalStatus, err)
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	for _, message := range messages {
+		rec.Failures = append(rec.Failures, color.ClearCode(message))
+	}
+
+	// Save the record even if the context was cancelled
+	saveErr := b.history.Save(context.Background(), *rec)
+	if saveErr != nil {
+		clio.Warnf("Could not save deployment history: %s", saveErr)
+	}
+}
+
+// History returns the records of past deploys and deletes matching q, with the most recent first
+func (b *Deployer) History(ctx context.Context, q history.Query) ([]history.Record, error) {
+	if b.history == nil {
+		return nil, nil
+	}
+	return b.history.List(ctx, q)
+}
+
+// CompareHistory compares two past runs by their record IDs
+func (b *Deployer) CompareHistory(ctx context.Context, fromID, toID string) (history.Comparison, error) {
+	if b.history == nil {
+		return history.Comparison{}, history.ErrNotFound
+	}
+	return history.CompareByID(ctx, b.history, fromID, toID)
+}
@@ -0,0 +1,370 @@
+package deployer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/smithy-go/ptr"
+	"github.com/common-fate/clio"
+	"github.com/common-fate/cloudform/cfn"
+	"github.com/common-fate/cloudform/ui"
+	"github.com/pkg/errors"
+)
+
+// ErrHookFailed is wrapped by the error returned when a deployment hook fails
+var ErrHookFailed = errors.New("deployment hook failed")
+
+// HookStage is the point in a deployment at which a hook runs
+type HookStage string
+
+const (
+	// HookPreCreate hooks run before the change set is created.
+	// If one fails, the deployment is aborted.
+	HookPreCreate HookStage = "pre-create"
+	// HookPostReview hooks run after the change set has been approved, before it is executed.
+	// If one fails, the deployment is aborted and the change set is left unexecuted.
+	HookPostReview HookStage = "post-review"
+	// HookPostExecute hooks run after the change set has been executed successfully.
+	// If one fails, the on-failure hooks run and the stack can be rolled back.
+	HookPostExecute HookStage = "post-execute"
+	// HookOnFailure hooks run after the stack operation or a post-execute hook fails.
+	// They don't run if the deployment stops before the change set is executed, such as
+	// when the change set can't be created, a policy or post-review hook rejects it, or
+	// it can't be executed, or if waiting for the stack returns an error.
+	// Their errors are reported but don't change the result.
+	HookOnFailure HookStage = "on-failure"
+)
+
+// HookContext is passed to a hook
+type HookContext struct {
+	Stage     HookStage `json:"stage"`
+	StackName string    `json:"stackName"`
+	// Outputs are the stack's outputs by key. Before the change set is executed they
+	// are the outputs of the existing stack, which is empty for a new stack.
+	Outputs map[string]string `json:"outputs"`
+	// ChangeSet describes the changes being deployed. It is nil for pre-create hooks.
+	ChangeSet *ui.ChangeSet `json:"changeSet,omitempty"`
+	// Summary is the rendered change set
+	Summary string `json:"summary,omitempty"`
+	// FinalStatus is the stack's status once the change set has been executed
+	FinalStatus string `json:"finalStatus,omitempty"`
+	// Error is why the deployment failed, for on-failure hooks
+	Error string `json:"error,omitempty"`
+	// Output is where hooks should write anything they print, which is the Deployer's terminal
+	Output io.Writer `json:"-"`
+}
+
+// HookFunc is the function a hook runs
+type HookFunc func(ctx context.Context, hc HookContext) error
+
+// Hook runs at a stage of a deployment. See DeployOpts.Hooks.
+type Hook struct {
+	// Name identifies the hook in messages
+	Name  string
+	Stage HookStage
+	Run   HookFunc
+	// RollbackOnFailure redeploys the template and parameters the stack had before the
+	// deployment if this post-execute hook fails. Parameters marked NoEcho keep their new values,
+	// as CloudFormation doesn't return their previous values.
+	RollbackOnFailure bool
+}
+
+// CommandHook returns a hook which runs a shell command. The command's output is written to
+// the hook context's Output. The hook context is written to the command's stdin as JSON,
+// and is also available in environment variables:
+// CLOUDFORM_STAGE, CLOUDFORM_STACK_NAME, CLOUDFORM_FINAL_STATUS and
+// CLOUDFORM_OUTPUT_<key> for each stack output.
+// The hook fails if the command exits with a non-zero status.
+func CommandHook(stage HookStage, command string) Hook {
+	return Hook{
+		Name:  command,
+		Stage: stage,
+		Run: func(ctx context.Context, hc HookContext) error {
+			input, err := json.Marshal(hc)
+			if err != nil {
+				return err
+			}
+
+			var cmd *exec.Cmd
+			if runtime.GOOS == "windows" {
+				cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+			} else {
+				cmd = exec.CommandContext(ctx, "sh", "-c", command)
+			}
+
+			cmd.Stdin = strings.NewReader(string(input))
+			out := hc.Output
+			if out == nil {
+				out = os.Stderr
+			}
+			cmd.Stdout = out
+			cmd.Stderr = out
+			cmd.Env = append(os.Environ(), hookEnv(hc)...)
+
+			return cmd.Run()
+		},
+	}
+}
+
+// hookEnv returns the environment variables describing a hook context
+func hookEnv(hc HookContext) []string {
+	env := []string{
+		"CLOUDFORM_STAGE=" + string(hc.Stage),
+		"CLOUDFORM_STACK_NAME=" + hc.StackName,
+		"CLOUDFORM_FINAL_STATUS=" + hc.FinalStatus,
+	}
+	for k, v := range hc.Outputs {
+		env = append(env, fmt.Sprintf("CLOUDFORM_OUTPUT_%s=%s", k, v))
+	}
+	return env
+}
+
+// stackOutputs returns a stack's outputs by key
+func stackOutputs(stack types.Stack) map[string]string {
+	outputs := make(map[string]string)
+	for _, o := range stack.Outputs {
+		outputs[ptr.ToString(o.OutputKey)] = ptr.ToString(o.OutputValue)
+	}
+	return outputs
+}
+
+// currentOutputs returns the outputs of a stack, or none if the stack doesn't exist yet
+func (b *Deployer) currentOutputs(ctx context.Context, stackName string) (map[string]string, error) {
+	stack, err := b.cloudformClient.GetStack(ctx, stackName)
+	if err == cfn.ErrStackNotExist {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "describing stack %s", stackName)
+	}
+
+	return stackOutputs(stack), nil
+}
+
+// hookFailure is a hook which returned an error
+type hookFailure struct {
+	hook Hook
+	err  error
+}
+
+// runHooks runs the hooks for hc.Stage in order. Unless keepGoing is set,
+// it stops at the first hook which fails.
+func runHooks(ctx context.Context, hooks []Hook, hc HookContext, keepGoing bool) []hookFailure {
+	var failures []hookFailure
+
+	for _, h := range hooks {
+		if h.Stage != hc.Stage {
+			continue
+		}
+
+		clio.Infof("Running %s hook %s", hc.Stage, h.Name)
+
+		err := h.Run(ctx, hc)
+		if err != nil {
+			failures = append(failures, hookFailure{hook: h, err: err})
+			if !keepGoing {
+				break
+			}
+		}
+	}
+
+	return failures
+}
+
+// hookError returns an error wrapping ErrHookFailed for the failed hooks, or nil if there are none
+func hookError(stage HookStage, failures []hookFailure) error {
+	if len(failures) == 0 {
+		return nil
+	}
+
+	messages := make([]string, len(failures))
+	for i, f := range failures {
+		messages[i] = fmt.Sprintf("%s: %s", f.hook.Name, f.err)
+	}
+
+	return fmt.Errorf("%w: %s %s", ErrHookFailed, stage, strings.Join(messages, "; "))
+}
+
+// wantsRollback returns true if any of the failed hooks should roll the stack back
+func wantsRollback(failures []hookFailure) bool {
+	for _, f := range failures {
+		if f.hook.RollbackOnFailure {
+			return true
+		}
+	}
+	return false
+}
+
+// hasRollbackHooks returns true if any post-execute hook can roll the stack back
+func hasRollbackHooks(hooks []Hook) bool {
+	for _, h := range hooks {
+		if h.Stage == HookPostExecute && h.RollbackOnFailure {
+			return true
+		}
+	}
+	return false
+}
+
+// previousDeployment is the template and parameters of a stack before it was deployed,
+// used to roll back when a post-execute hook fails
+type previousDeployment struct {
+	template string
+	params   []types.Parameter
+}
+
+// savePrevious records the template and parameters of an existing stack.
+// It returns nil for stacks which are being created.
+func (b *Deployer) savePrevious(ctx context.Context, plan *Plan) (*previousDeployment, error) {
+	if plan.StackStatus == "REVIEW_IN_PROGRESS" {
+		return nil, nil
+	}
+
+	stack, err := b.cloudformClient.GetStack(ctx, plan.StackName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "describing stack %s", plan.StackName)
+	}
+
+	template, err := b.cloudformClient.GetTemplate(ctx, plan.StackName, "")
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting the current template of %s", plan.StackName)
+	}
+
+	prev := previousDeployment{template: template}
+	for _, p := range stack.Parameters {
+		param := types.Parameter{ParameterKey: p.ParameterKey}
+		if ptr.ToString(p.ParameterValue) == "****" {
+			// NoEcho values aren't returned, so the best we can do is keep the current value
+			param.UsePreviousValue = ptr.Bool(true)
+		} else {
+			param.ParameterValue = p.ParameterValue
+		}
+		prev.params = append(prev.params, param)
+	}
+
+	return &prev, nil
+}
+
+// rollback redeploys the stack's previous template and parameters
+func (b *Deployer) rollback(ctx context.Context, plan *Plan, prev *previousDeployment, opts ApplyOpts) error {
+	if prev == nil {
+		clio.Warnf("Not rolling back %s, it didn't exist before this deployment", plan.StackName)
+		return nil
+	}
+
+	clio.Infof("Rolling back %s to its previous template", plan.StackName)
+
+	rollbackPlan, err := b.Plan(ctx, DeployOpts{
+		StackName: plan.StackName,
+		Template:  prev.template,
+		Params:    prev.params,
+		Tags:      plan.Tags,
+		RoleARN:   opts.RoleARN,
+		// The template was deployed before, so there's no need to check it again
+		SkipValidation: true,
+	})
+	if err == ErrNoChanges {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "planning rollback")
+	}
+
+	// The rollback returns the stack to a template which was already deployed,
+	// so the deployment's policies and hooks don't apply to it
+	rollbackOpts := opts
+	rollbackOpts.Hooks = nil
+	rollbackOpts.Policy = nil
+	rollbackOpts.OverridePolicy = false
+	rollbackOpts.StackPolicy = ""
+	rollbackOpts.StackPolicyDuringUpdate = ""
+
+	_, err = b.apply(ctx, rollbackPlan, rollbackOpts)
+	if err != nil {
+		return errors.Wrap(err, "rolling back")
+	}
+
+	return nil
+}
+
+// runPreCreateHooks runs the pre-create hooks with the outputs of the existing stack
+func (b *Deployer) runPreCreateHooks(ctx context.Context, opts DeployOpts) error {
+	if !hasStage(opts.Hooks, HookPreCreate) {
+		return nil
+	}
+
+	outputs, err := b.currentOutputs(ctx, opts.StackName)
+	if err != nil {
+		return err
+	}
+
+	hc := HookContext{
+		Stage:     HookPreCreate,
+		StackName: opts.StackName,
+		Outputs:   outputs,
+		Output:    b.term,
+	}
+
+	return hookError(HookPreCreate, runHooks(ctx, opts.Hooks, hc, false))
+}
+
+// runPostExecuteHooks runs the post-execute hooks with the stack's new outputs.
+// If one fails, the on-failure hooks run and the stack is rolled back if the hook asks for it.
+func (b *Deployer) runPostExecuteHooks(ctx context.Context, plan *Plan, prev *previousDeployment, opts ApplyOpts, hc HookContext, res *DeployResult) error {
+	if !hasStage(opts.Hooks, HookPostExecute) {
+		return nil
+	}
+
+	outputs, err := b.currentOutputs(ctx, plan.StackName)
+	if err != nil {
+		return err
+	}
+
+	hc.Stage = HookPostExecute
+	hc.Outputs = outputs
+
+	failures := runHooks(ctx, opts.Hooks, hc, false)
+	hookErr := hookError(HookPostExecute, failures)
+	if hookErr == nil {
+		return nil
+	}
+
+	b.runFailureHooks(ctx, opts, hc, hookErr)
+
+	if wantsRollback(failures) {
+		err = b.rollback(ctx, plan, prev, opts)
+		if err != nil {
+			return fmt.Errorf("%w (and the rollback failed: %s)", hookErr, err)
+		}
+		res.RolledBack = prev != nil
+	}
+
+	return hookErr
+}
+
+// runFailureHooks runs the on-failure hooks. Their errors are reported as warnings
+// so that they don't hide the original failure.
+func (b *Deployer) runFailureHooks(ctx context.Context, opts ApplyOpts, hc HookContext, cause error) {
+	hc.Stage = HookOnFailure
+	hc.Error = cause.Error()
+
+	for _, f := range runHooks(ctx, opts.Hooks, hc, true) {
+		clio.Warnf("%s hook %s failed: %s", HookOnFailure, f.hook.Name, f.err)
+	}
+}
+
+// hasStage returns true if any hook runs at stage
+func hasStage(hooks []Hook, stage HookStage) bool {
+	for _, h := range hooks {
+		if h.Stage == stage {
+			return true
+		}
+	}
+	return false
+}
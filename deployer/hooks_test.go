@@ -0,0 +1,116 @@
+package deployer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/smithy-go/ptr"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRunHooks(t *testing.T) {
+	var ran []string
+	hook := func(name string, stage HookStage, err error) Hook {
+		return Hook{Name: name, Stage: stage, Run: func(ctx context.Context, hc HookContext) error {
+			ran = append(ran, name)
+			return err
+		}}
+	}
+
+	hooks := []Hook{
+		hook("migrate", HookPreCreate, nil),
+		hook("smoke test", HookPostExecute, errors.New("exit status 1")),
+		hook("check", HookPostExecute, nil),
+		hook("notify", HookOnFailure, errors.New("webhook unavailable")),
+		hook("page", HookOnFailure, nil),
+	}
+
+	failures := runHooks(context.Background(), hooks, HookContext{Stage: HookPostExecute}, false)
+	if diff := cmp.Diff([]string{"smoke test"}, ran); diff != "" {
+		t.Errorf("post-execute hooks should stop at the first failure: %s", diff)
+	}
+
+	err := hookError(HookPostExecute, failures)
+	if !errors.Is(err, ErrHookFailed) || !strings.Contains(err.Error(), "smoke test: exit status 1") {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	ran = nil
+	failures = runHooks(context.Background(), hooks, HookContext{Stage: HookOnFailure}, true)
+	if diff := cmp.Diff([]string{"notify", "page"}, ran); diff != "" {
+		t.Errorf("on-failure hooks should all run: %s", diff)
+	}
+	if len(failures) != 1 || wantsRollback(failures) {
+		t.Errorf("unexpected failures %+v", failures)
+	}
+
+	if hookError(HookPreCreate, nil) != nil {
+		t.Error("expected no error without failures")
+	}
+}
+
+func TestCommandHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+
+	hook := CommandHook(HookPostExecute, `cat > `+out+`.json && echo "$CLOUDFORM_STAGE $CLOUDFORM_STACK_NAME $CLOUDFORM_OUTPUT_BucketName" > `+out)
+	hc := HookContext{
+		Stage:       HookPostExecute,
+		StackName:   "app",
+		Outputs:     stackOutputs(types.Stack{Outputs: []types.Output{{OutputKey: ptr.String("BucketName"), OutputValue: ptr.String("app-bucket")}}}),
+		FinalStatus: "UPDATE_COMPLETE",
+	}
+
+	err := hook.Run(context.Background(), hc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(env)); got != "post-execute app app-bucket" {
+		t.Errorf("unexpected environment %q", got)
+	}
+
+	input, err := os.ReadFile(out + ".json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got HookContext
+	err = json.Unmarshal(input, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(hc, got); diff != "" {
+		t.Errorf("unexpected stdin: %s", diff)
+	}
+
+	var printed bytes.Buffer
+	hc.Output = &printed
+	echo := CommandHook(HookPostExecute, "echo done && echo oops >&2")
+	if err := echo.Run(context.Background(), hc); err != nil {
+		t.Fatal(err)
+	}
+	if got := printed.String(); got != "done\noops\n" {
+		t.Errorf("expected the command's output to go to hc.Output, got %q", got)
+	}
+
+	failing := CommandHook(HookPreCreate, "exit 3")
+	if err := failing.Run(context.Background(), hc); err == nil {
+		t.Error("expected a failing command to return an error")
+	}
+}
@@ -0,0 +1,45 @@
+package deployer
+
+import (
+	"context"
+
+	"github.com/common-fate/clio"
+	"github.com/common-fate/cloudform/lock"
+)
+
+// lockKey is the key of a stack's lock. Stacks with the same name in different regions are locked separately.
+func (b *Deployer) lockKey(stackName string) string {
+	return b.region + "/" + stackName
+}
+
+// lockStack takes the stack's lock, waiting for it if the lock options say to.
+// The returned function releases the lock. If locking is disabled, it does nothing.
+func (b *Deployer) lockStack(ctx context.Context, stackName string) (func(), error) {
+	if b.locker == nil {
+		return func() {}, nil
+	}
+
+	opts := b.lockOpts
+	if opts.OnWait == nil {
+		lastHolder := ""
+		opts.OnWait = func(h lock.Holder) {
+			if h.ID != lastHolder {
+				clio.Infof("Waiting for stack %s to be unlocked, it is locked by %s until %s", stackName, h, h.ExpiresAt.Format("15:04:05"))
+				lastHolder = h.ID
+			}
+		}
+	}
+
+	lease, err := lock.Acquire(ctx, b.locker, b.lockKey(stackName), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() {
+		// Release even if ctx has been cancelled, so the lock doesn't have to expire
+		err := lease.Release(context.Background())
+		if err != nil {
+			clio.Warnf("Could not release the lock on stack %s: %s", stackName, err)
+		}
+	}, nil
+}
@@ -0,0 +1,369 @@
+package deployer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/smithy-go/ptr"
+	"github.com/common-fate/cloudform/cfn"
+	"github.com/common-fate/cloudform/console/spinner"
+	"github.com/common-fate/cloudform/policy"
+	"github.com/common-fate/cloudform/status"
+	"github.com/common-fate/cloudform/ui"
+	"github.com/pkg/errors"
+)
+
+// ErrNoChanges is returned by Plan when the
+// change set doesn't contain any changes.
+var ErrNoChanges = errors.New("there are no changes in the changeset")
+
+// Plan is a durable handle to a change set which has been
+// created but not yet executed. Plans can be serialised to JSON,
+// reviewed, and passed to Apply at a later time.
+type Plan struct {
+	// StackName is the name of the stack the change set belongs to
+	StackName string `json:"stackName"`
+	// StackID is the ARN of the stack
+	StackID string `json:"stackId"`
+	// ChangeSetName is the name of the change set
+	ChangeSetName string `json:"changeSetName"`
+	// ChangeSetID is the ARN of the change set
+	ChangeSetID string `json:"changeSetId"`
+	// TemplateHash is the hex encoded SHA256 hash of the change set's template,
+	// as CloudFormation stores it. Hashing the stored template rather than the one
+	// passed to Plan means templates given as a URL are hashed by their contents.
+	// Apply checks that the change set still has this template.
+	TemplateHash string `json:"templateHash"`
+	// Summary is a rendered representation of the changes
+	Summary string `json:"summary"`
+	// ChangeSet describes the changes, including nested change sets
+	ChangeSet *ui.ChangeSet `json:"changeSet"`
+	// StackStatus is the status of the stack when the plan was made
+	StackStatus string `json:"stackStatus"`
+	// StackLastUpdatedTime is the last time the stack was updated
+	// before the plan was made. It is nil for stacks which have never been updated.
+	StackLastUpdatedTime *time.Time `json:"stackLastUpdatedTime,omitempty"`
+	// Tags are the stack tags the plan was made with, which ApplyOpts.Policy rules can match on
+	Tags map[string]string `json:"tags,omitempty"`
+	// CreatedAt is the time the plan was made
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// HashTemplate returns the hex encoded SHA256 hash of a template
+func HashTemplate(template string) string {
+	sum := sha256.Sum256([]byte(template))
+	return hex.EncodeToString(sum[:])
+}
+
+// Plan creates a change set for the stack without executing it.
+// The returned plan can be passed to Apply to execute the change set.
+// ErrNoChanges is returned if there is nothing to deploy.
+// The template is validated first, then pre-create hooks in opts run;
+// if either fails, no change set is created.
+func (b *Deployer) Plan(ctx context.Context, opts DeployOpts) (*Plan, error) {
+	err := opts.validateRollbackConfiguration()
+	if err != nil {
+		return nil, err
+	}
+
+	err = b.validateTemplate(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	err = b.runPreCreateHooks(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	si := spinner.New(spinner.WithTerminal(b.term))
+	si.Push("creating CloudFormation change set")
+
+	changeSetName, createErr := b.cloudformClient.CreateChangeSet(ctx, opts.Template, opts.Params, opts.Tags, opts.StackName, opts.RoleARN, opts.requestOpts()...)
+
+	si.Stop()
+
+	if createErr != nil {
+		if createErr.Error() == noChangeFoundMsg {
+			return nil, ErrNoChanges
+		}
+
+		return nil, errors.Wrap(createErr, "creating changeset")
+	}
+
+	changeSet, err := b.cloudformClient.GetChangeSet(ctx, opts.StackName, changeSetName)
+	if err != nil {
+		return nil, errors.Wrap(err, "describing changeset")
+	}
+
+	template, err := b.cloudformClient.GetTemplate(ctx, opts.StackName, ptr.ToString(changeSet.ChangeSetId))
+	if err != nil {
+		return nil, errors.Wrap(err, "getting changeset template")
+	}
+
+	stack, err := b.cloudformClient.GetStack(ctx, opts.StackName)
+	if err != nil {
+		return nil, errors.Wrap(err, "describing stack")
+	}
+
+	description, err := b.uiClient.DescribeChangeSet(ctx, opts.StackName, changeSetName)
+	if err != nil {
+		return nil, err
+	}
+
+	p := Plan{
+		StackName:            opts.StackName,
+		StackID:              ptr.ToString(changeSet.StackId),
+		ChangeSetName:        changeSetName,
+		ChangeSetID:          ptr.ToString(changeSet.ChangeSetId),
+		TemplateHash:         HashTemplate(template),
+		Summary:              ui.RenderChangeSet(description),
+		ChangeSet:            description,
+		StackStatus:          string(stack.StackStatus),
+		StackLastUpdatedTime: stack.LastUpdatedTime,
+		Tags:                 opts.Tags,
+		CreatedAt:            time.Now(),
+	}
+
+	return &p, nil
+}
+
+// ApplyOpts configures Apply. What the change set contains, such as the template
+// and parameters, was decided when the plan was made.
+type ApplyOpts struct {
+	// RoleARN is an optional deployment role, used if a hook rolls the stack back
+	RoleARN string
+	// Policy is an optional set of rules which the change set
+	// is checked against before it is executed
+	Policy *policy.Policy
+	// OverridePolicy allows overridable policy violations
+	// to proceed without an interactive confirmation
+	OverridePolicy bool
+	// StackPolicy is an optional stack policy body which
+	// is applied to the stack once the deployment has finished
+	StackPolicy string
+	// StackPolicyDuringUpdate is an optional stack policy body which temporarily
+	// replaces the stack's policy while an existing stack is being updated
+	StackPolicyDuringUpdate string
+	// TerminationProtection enables or disables termination protection
+	// once the deployment has finished. It is left unchanged if nil.
+	TerminationProtection *bool
+	// Dashboard shows progress in a full screen dashboard rather than
+	// inline, if the console is interactive
+	Dashboard bool
+	// WaitForCleanup waits for old resources to be removed after an update
+	// before returning. Otherwise Apply returns as soon as the update has succeeded.
+	WaitForCleanup bool
+	// DisableRollback leaves the stack in UPDATE_FAILED if the deployment fails.
+	// Use RollbackStack to return the stack to its last stable state. For new stacks
+	// it is ignored, as whether they are rolled back is decided when the change set
+	// is created, by DeployOpts.DisableRollback.
+	DisableRollback bool
+	// Hooks run at stages of the deployment, in order. Pre-create hooks don't run,
+	// as the change set has already been created.
+	Hooks []Hook
+}
+
+// applyOpts returns the options which apply to executing the change set
+func (opts DeployOpts) applyOpts() ApplyOpts {
+	return ApplyOpts{
+		RoleARN:                 opts.RoleARN,
+		Policy:                  opts.Policy,
+		OverridePolicy:          opts.OverridePolicy,
+		StackPolicy:             opts.StackPolicy,
+		StackPolicyDuringUpdate: opts.StackPolicyDuringUpdate,
+		TerminationProtection:   opts.TerminationProtection,
+		Dashboard:               opts.Dashboard,
+		WaitForCleanup:          opts.WaitForCleanup,
+		DisableRollback:         opts.DisableRollback,
+		Hooks:                   opts.Hooks,
+	}
+}
+
+// Apply executes the change set referenced by a plan and waits for the stack to settle.
+// An error is returned if the change set is no longer available, if its template
+// doesn't match plan.TemplateHash, if the stack has been modified since the plan was made,
+// or if the change set breaks opts.Policy.
+// If the deployment fails, the result is returned along with an error wrapping ErrDeployFailed.
+//
+// If the change set is already being executed, for example because a previous
+// call to Apply was interrupted, Apply resumes waiting for it instead.
+// Post-review, post-execute and on-failure hooks in opts run around the execution.
+// The stack is locked while the change set is executed; see WithLock.
+func (b *Deployer) Apply(ctx context.Context, plan *Plan, opts ApplyOpts) (*DeployResult, error) {
+	unlock, err := b.lockStack(ctx, plan.StackName)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	return b.apply(ctx, plan, opts)
+}
+
+// apply is Apply for callers which already hold the stack's lock
+func (b *Deployer) apply(ctx context.Context, plan *Plan, opts ApplyOpts) (*DeployResult, error) {
+	err := b.checkPlan(ctx, plan)
+	if err == errPlanExecuting {
+		return b.resume(ctx, plan.StackName, plan.settleRules(opts))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = b.checkPolicy(ctx, plan, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs, err := b.currentOutputs(ctx, plan.StackName)
+	if err != nil {
+		return nil, err
+	}
+
+	hc := HookContext{
+		Stage:     HookPostReview,
+		StackName: plan.StackName,
+		Outputs:   outputs,
+		ChangeSet: plan.ChangeSet,
+		Summary:   plan.Summary,
+		Output:    b.term,
+	}
+
+	err = hookError(HookPostReview, runHooks(ctx, opts.Hooks, hc, false))
+	if err != nil {
+		return nil, err
+	}
+
+	var prev *previousDeployment
+	if hasRollbackHooks(opts.Hooks) {
+		prev, err = b.savePrevious(ctx, plan)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	restorePolicy, err := b.overrideStackPolicy(ctx, plan, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// The original policy is put back however apply returns, unless
+	// opts.StackPolicy replaces it once the deployment has finished
+	var policyReplaced bool
+	if restorePolicy != "" {
+		defer func() {
+			if !policyReplaced {
+				b.restoreStackPolicy(plan.StackName, restorePolicy)
+			}
+		}()
+	}
+
+	// New stacks were given their rollback behaviour when the change set was created
+	disableRollback := opts.DisableRollback && plan.settleRules(opts).Operation != status.OperationCreate
+
+	err = b.cloudformClient.ExecuteChangeSet(ctx, plan.StackName, plan.ChangeSetID, cfn.WithClientRequestToken(executeToken(plan.ChangeSetID)), cfn.WithDisableRollback(disableRollback))
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := b.waitForStack(ctx, plan.StackName, opts.Dashboard, plan.settleRules(opts))
+	if err != nil {
+		return nil, err
+	}
+
+	policyReplaced, err = b.applyStackSettings(ctx, plan.StackName, result.Status, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	res := DeployResult{
+		FinalStatus:       result.Status,
+		Messages:          result.Messages,
+		RollbackTriggered: result.RollbackTriggered,
+	}
+
+	hc.FinalStatus = result.Status
+
+	err = outcomeError(result)
+	if err != nil {
+		if leftFailed(result.Status) {
+			res.FailedResources = b.failedResources(ctx, plan.StackName)
+		}
+		b.runFailureHooks(ctx, opts, hc, err)
+		return &res, err
+	}
+
+	return &res, b.runPostExecuteHooks(ctx, plan, prev, opts, hc, &res)
+}
+
+// settleRules returns the rules for waiting for the plan's change set to be executed.
+// Stacks which are in review when the plan is made are being created.
+func (p *Plan) settleRules(opts ApplyOpts) status.SettleRules {
+	rules := status.SettleRules{
+		Operation:      status.OperationUpdate,
+		WaitForCleanup: opts.WaitForCleanup,
+	}
+
+	if p.StackStatus == "REVIEW_IN_PROGRESS" {
+		rules.Operation = status.OperationCreate
+	}
+
+	return rules
+}
+
+// PlanFile returns a plan file for the plan which can be saved and rendered offline
+func (p *Plan) PlanFile() *ui.PlanFile {
+	return ui.NewPlanFile(p.ChangeSet)
+}
+
+// errPlanExecuting is returned by checkPlan if the change set is already being executed
+var errPlanExecuting = errors.New("changeset is being executed")
+
+// checkPlan returns an error if the plan can no longer be safely applied
+func (b *Deployer) checkPlan(ctx context.Context, plan *Plan) error {
+	changeSet, err := b.cloudformClient.GetChangeSet(ctx, plan.StackName, plan.ChangeSetID)
+	if err != nil {
+		return errors.Wrap(err, "describing changeset")
+	}
+
+	if changeSet.ExecutionStatus == types.ExecutionStatusExecuteInProgress {
+		return errPlanExecuting
+	}
+
+	if changeSet.ExecutionStatus != types.ExecutionStatusAvailable {
+		return errors.Errorf("changeset %s can't be executed: execution status is %s", plan.ChangeSetName, changeSet.ExecutionStatus)
+	}
+
+	template, err := b.cloudformClient.GetTemplate(ctx, plan.StackName, plan.ChangeSetID)
+	if err != nil {
+		return errors.Wrap(err, "getting changeset template")
+	}
+
+	if HashTemplate(template) != plan.TemplateHash {
+		return errors.Errorf("changeset %s doesn't have the template the plan was made with", plan.ChangeSetName)
+	}
+
+	stack, err := b.cloudformClient.GetStack(ctx, plan.StackName)
+	if err == cfn.ErrStackNotExist {
+		return errors.Errorf("stack %s no longer exists", plan.StackName)
+	}
+	if err != nil {
+		return errors.Wrap(err, "describing stack")
+	}
+
+	if string(stack.StackStatus) != plan.StackStatus || !sameTime(stack.LastUpdatedTime, plan.StackLastUpdatedTime) {
+		return errors.Errorf("stack %s has changed since the plan was made (status is now %s)", plan.StackName, stack.StackStatus)
+	}
+
+	return nil
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
@@ -0,0 +1,151 @@
+package deployer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/aws-cloudformation/rain/cft/parse"
+	"github.com/common-fate/clio"
+	"github.com/common-fate/cloudform/policy"
+	"github.com/common-fate/cloudform/ui"
+)
+
+// checkPolicy evaluates the plan against opts.Policy. Violations which can be
+// overridden require either opts.OverridePolicy or an interactive confirmation,
+// regardless of DeployOpts.Confirm.
+func (b *Deployer) checkPolicy(ctx context.Context, plan *Plan, opts ApplyOpts) error {
+	if opts.Policy == nil || plan.ChangeSet == nil {
+		return nil
+	}
+
+	changes := b.policyChanges(ctx, plan.StackName, plan.ChangeSet, "", plan.Tags)
+
+	violations := opts.Policy.Evaluate(changes)
+	if len(violations) == 0 {
+		return nil
+	}
+
+	verr := &policy.ViolationError{Violations: violations}
+
+	if !verr.Overridable() {
+		return verr
+	}
+
+	if opts.OverridePolicy {
+		clio.Warnf("Overriding deployment policy: %s", verr.Error())
+		return nil
+	}
+
+	if !b.term.IsInteractive() {
+		return verr
+	}
+
+	clio.Warn(verr.Error())
+
+	var answer string
+	p := &survey.Input{Message: fmt.Sprintf("Type the stack name (%s) to override the policy:", plan.StackName)}
+	err := survey.AskOne(p, &answer)
+	if err != nil {
+		return err
+	}
+
+	if answer != plan.StackName {
+		return verr
+	}
+
+	return nil
+}
+
+// policyChanges flattens a change set, including nested change sets, into policy changes.
+// Resource tags are read from the current stack template for modified and removed resources,
+// and from the change set template for added resources. Stack tags apply to every resource.
+func (b *Deployer) policyChanges(ctx context.Context, stackName string, cs *ui.ChangeSet, prefix string, stackTags map[string]string) []policy.Change {
+	var currentTags, newTags map[string]map[string]string
+
+	// We ignore errors because it just means the rules can't match on resource tags
+	if cs.StackID != "" {
+		if body, err := b.cloudformClient.GetTemplate(ctx, cs.StackID, ""); err == nil {
+			currentTags = templateTags(body)
+		}
+	}
+	if cs.ChangeSetID != "" {
+		if body, err := b.cloudformClient.GetTemplate(ctx, cs.StackID, cs.ChangeSetID); err == nil {
+			newTags = templateTags(body)
+		}
+	}
+
+	changes := make([]policy.Change, 0)
+
+	for _, rc := range cs.Changes {
+		tags := make(map[string]string)
+		for k, v := range stackTags {
+			tags[k] = v
+		}
+
+		resourceTags := currentTags[rc.LogicalResourceID]
+		if rc.Action == "Add" {
+			resourceTags = newTags[rc.LogicalResourceID]
+		}
+		for k, v := range resourceTags {
+			tags[k] = v
+		}
+
+		changes = append(changes, policy.Change{
+			StackName:    stackName,
+			Path:         prefix + rc.LogicalResourceID,
+			Action:       rc.Action,
+			ResourceType: rc.ResourceType,
+			LogicalID:    rc.LogicalResourceID,
+			Replacement:  rc.Replacement,
+			Tags:         tags,
+		})
+
+		if rc.Nested != nil {
+			changes = append(changes, b.policyChanges(ctx, stackName, rc.Nested, prefix+rc.LogicalResourceID+"/", stackTags)...)
+		}
+	}
+
+	return changes
+}
+
+// templateTags returns the literal tags of each resource in a template, keyed by logical ID.
+// Both the list ([{Key, Value}]) and map forms of the Tags property are supported.
+func templateTags(body string) map[string]map[string]string {
+	out := make(map[string]map[string]string)
+
+	t, err := parse.String(body)
+	if err != nil {
+		return out
+	}
+
+	resources, _ := t.Map()["Resources"].(map[string]interface{})
+	for id, r := range resources {
+		resource, _ := r.(map[string]interface{})
+		props, _ := resource["Properties"].(map[string]interface{})
+
+		tags := make(map[string]string)
+
+		switch v := props["Tags"].(type) {
+		case []interface{}:
+			for _, item := range v {
+				tag, _ := item.(map[string]interface{})
+				key, keyOK := tag["Key"].(string)
+				value, valueOK := tag["Value"].(string)
+				if keyOK && valueOK {
+					tags[key] = value
+				}
+			}
+		case map[string]interface{}:
+			for key, item := range v {
+				if value, ok := item.(string); ok {
+					tags[key] = value
+				}
+			}
+		}
+
+		out[id] = tags
+	}
+
+	return out
+}
@@ -0,0 +1,108 @@
+package deployer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/common-fate/clio"
+	"github.com/common-fate/cloudform/status"
+	"github.com/pkg/errors"
+)
+
+// allowAllStackPolicy is restored after a temporary
+// override when the stack previously had no stack policy
+const allowAllStackPolicy = `{"Statement":[{"Effect":"Allow","Action":"Update:*","Principal":"*","Resource":"*"}]}`
+
+// overrideStackPolicy applies opts.StackPolicyDuringUpdate to an existing stack.
+// It returns the policy which should be restored once the update has finished,
+// or an empty string if the policy was not overridden.
+func (b *Deployer) overrideStackPolicy(ctx context.Context, plan *Plan, opts ApplyOpts) (string, error) {
+	if opts.StackPolicyDuringUpdate == "" || plan.StackStatus == "REVIEW_IN_PROGRESS" {
+		return "", nil
+	}
+
+	original, err := b.cloudformClient.GetStackPolicy(ctx, plan.StackName)
+	if err != nil {
+		return "", errors.Wrap(err, "getting stack policy")
+	}
+	if original == "" {
+		original = allowAllStackPolicy
+	}
+
+	err = b.cloudformClient.SetStackPolicy(ctx, plan.StackName, opts.StackPolicyDuringUpdate)
+	if err != nil {
+		return "", errors.Wrap(err, "setting temporary stack policy")
+	}
+
+	return original, nil
+}
+
+// applyStackSettings sets opts.StackPolicy and termination protection once a
+// deployment has finished. It returns true if the stack policy was set.
+func (b *Deployer) applyStackSettings(ctx context.Context, stackName, stackStatus string, opts ApplyOpts) (bool, error) {
+	// Stacks which failed to create, or failed to roll back, can't be updated
+	if !status.Stack(stackStatus).IsUpdatable() {
+		return false, nil
+	}
+
+	if opts.StackPolicy != "" {
+		err := b.cloudformClient.SetStackPolicy(ctx, stackName, opts.StackPolicy)
+		if err != nil {
+			return false, errors.Wrap(err, "setting stack policy")
+		}
+	}
+
+	if opts.TerminationProtection != nil {
+		err := b.cloudformClient.UpdateTerminationProtection(ctx, stackName, *opts.TerminationProtection)
+		if err != nil {
+			return opts.StackPolicy != "", errors.Wrap(err, "updating termination protection")
+		}
+	}
+
+	return opts.StackPolicy != "", nil
+}
+
+// restoreStackPolicy puts back the policy which overrideStackPolicy replaced.
+// It uses its own context so that it still runs if the deployment's context is cancelled,
+// and only warns on failure so that it doesn't hide the deployment's result.
+func (b *Deployer) restoreStackPolicy(stackName, policy string) {
+	err := b.cloudformClient.SetStackPolicy(context.Background(), stackName, policy)
+	if err != nil {
+		clio.Warnf("Failed to restore the stack policy of %s, it still has the policy used during the update: %s", stackName, err)
+	}
+}
+
+// checkTerminationProtection turns off termination protection before a stack is deleted,
+// either because opts.DisableTerminationProtection is set or because the user agreed to it.
+func (b *Deployer) checkTerminationProtection(ctx context.Context, opts DeleteOpts) error {
+	stack, err := b.cloudformClient.GetStack(ctx, opts.StackName)
+	if err != nil {
+		return err
+	}
+
+	if stack.EnableTerminationProtection == nil || !*stack.EnableTerminationProtection {
+		return nil
+	}
+
+	disable := opts.DisableTerminationProtection
+
+	if !disable {
+		if !b.term.IsInteractive() {
+			return fmt.Errorf("stack %s has termination protection enabled", opts.StackName)
+		}
+
+		p := &survey.Confirm{Message: fmt.Sprintf("Stack %s has termination protection enabled. Disable it and continue?", opts.StackName), Default: false}
+		err = survey.AskOne(p, &disable)
+		if err != nil {
+			return err
+		}
+		if !disable {
+			return ErrDeleteCancelled
+		}
+	}
+
+	clio.Infof("Disabling termination protection on %s", opts.StackName)
+
+	return b.cloudformClient.UpdateTerminationProtection(ctx, opts.StackName, false)
+}
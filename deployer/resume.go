@@ -0,0 +1,124 @@
+package deployer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/smithy-go/ptr"
+	"github.com/common-fate/clio"
+	"github.com/common-fate/cloudform/cfn"
+	"github.com/common-fate/cloudform/status"
+	"github.com/common-fate/cloudform/ui"
+	"github.com/pkg/errors"
+)
+
+// ErrStackInProgress is returned when a deployment is attempted
+// while the stack is already being modified. Use Resume to
+// attach to the existing operation.
+var ErrStackInProgress = errors.New("stack has an operation in progress")
+
+// maxTokenLength is the maximum length of a CloudFormation ClientRequestToken
+const maxTokenLength = 128
+
+// Resume attaches to an operation which is already in progress on the named stack,
+// renders its progress from its current state, and returns the final status once it settles.
+// If the stack has already settled, its current status is returned immediately.
+// If the operation failed, the result is returned along with an error wrapping
+// ErrDeployFailed, or ErrDeleteFailed if the stack was being deleted.
+func (b *Deployer) Resume(ctx context.Context, stackName string) (*DeployResult, error) {
+	return b.resume(ctx, stackName, status.SettleRules{})
+}
+
+// resume is Resume for callers which know which operation is running.
+// A create which is still in REVIEW_IN_PROGRESS is waited for if rules expect one,
+// rather than being treated as settled.
+func (b *Deployer) resume(ctx context.Context, stackName string, rules status.SettleRules) (*DeployResult, error) {
+	stack, err := b.cloudformClient.GetStack(ctx, stackName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "describing stack %s", stackName)
+	}
+
+	result := ui.WatchResult{
+		StackName: stackName,
+		Status:    string(stack.StackStatus),
+		Outcome:   rules.Outcome(status.Stack(stack.StackStatus)),
+	}
+
+	if stackInProgress(stack) || !result.Outcome.IsSettled() {
+		clio.Infof("Resuming %s (%s)", stackName, result.Status)
+		result, err = b.waitForStack(ctx, stackName, false, rules)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	res := DeployResult{
+		FinalStatus:       result.Status,
+		Messages:          result.Messages,
+		RollbackTriggered: result.RollbackTriggered,
+	}
+	if leftFailed(res.FinalStatus) {
+		res.FailedResources = b.failedResources(ctx, stackName)
+	}
+
+	return &res, outcomeError(result)
+}
+
+// stackInProgress returns true if the stack is being modified
+func stackInProgress(stack types.Stack) bool {
+	s := status.Stack(stack.StackStatus)
+	return s.IsInProgress() && s.Operation() != status.OperationReview
+}
+
+// checkNotInProgress returns ErrStackInProgress if the stack is being modified
+func (b *Deployer) checkNotInProgress(ctx context.Context, stackName string) error {
+	stack, err := b.cloudformClient.GetStack(ctx, stackName)
+	if err == cfn.ErrStackNotExist {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "describing stack %s", stackName)
+	}
+
+	if stackInProgress(stack) {
+		return fmt.Errorf("%w: %s is %s; use Resume to wait for it", ErrStackInProgress, stackName, stack.StackStatus)
+	}
+
+	return nil
+}
+
+// executeToken returns the ClientRequestToken used to execute a change set.
+// It is derived from the change set's ARN so that retrying the execution is idempotent,
+// while change sets which reuse a name, in this or another stack, get different tokens.
+func executeToken(changeSetID string) string {
+	sum := sha256.Sum256([]byte(changeSetID))
+	return token("exec", hex.EncodeToString(sum[:16]))
+}
+
+// deleteToken returns the ClientRequestToken used to delete a stack.
+// It is derived from the stack's identity and state so that retrying
+// a deletion which was interrupted is idempotent, while retrying a
+// deletion which failed is not.
+func deleteToken(stack types.Stack, retain []string) string {
+	parts := []string{ptr.ToString(stack.StackId), string(stack.StackStatus)}
+	if stack.DeletionTime != nil {
+		parts = append(parts, stack.DeletionTime.String())
+	}
+	parts = append(parts, retain...)
+
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\n")))
+
+	return token("delete", ptr.ToString(stack.StackName)+"-"+hex.EncodeToString(sum[:8]))
+}
+
+func token(prefix, id string) string {
+	t := fmt.Sprintf("cloudform-%s-%s", prefix, id)
+	if len(t) > maxTokenLength {
+		t = t[:maxTokenLength]
+	}
+	return t
+}
@@ -0,0 +1,55 @@
+package deployer
+
+import (
+	"context"
+	stderrors "errors"
+
+	"github.com/common-fate/clio"
+	"github.com/common-fate/cloudform/cfn"
+	"github.com/common-fate/cloudform/ui"
+	"github.com/common-fate/cloudform/validate"
+	"github.com/pkg/errors"
+)
+
+// ErrInvalidTemplate is the cause of the error returned by Plan when
+// validation finds errors in the template. Use errors.Cause to check for it.
+var ErrInvalidTemplate = errors.New("template is invalid")
+
+// validateTemplate checks the template locally and with CloudFormation's ValidateTemplate,
+// printing any findings. If ValidateTemplate isn't allowed, only the local checks are used.
+// An error wrapping ErrInvalidTemplate is returned if there are errors.
+// Warnings are printed but don't stop the deployment.
+func (b *Deployer) validateTemplate(ctx context.Context, opts DeployOpts) error {
+	if opts.SkipValidation {
+		return nil
+	}
+
+	// Templates which can't be parsed, and templates given as a URL, are
+	// left for CloudFormation to check
+	findings, _ := validate.String(opts.Template)
+
+	err := b.cloudformClient.ValidateTemplate(ctx, opts.Template)
+	if te, ok := errors.Cause(err).(*cfn.TemplateError); ok {
+		findings = append(findings, validate.Finding{
+			Severity: validate.Error,
+			Rule:     validate.RuleCloudFormation,
+			Message:  te.Message,
+		})
+	} else if stderrors.Is(err, cfn.ErrAccessDenied) {
+		// CloudFormation checks the template again when the change set is created,
+		// so the local findings are enough to carry on with
+		clio.Warnf("Skipping CloudFormation's template validation for %s: cloudformation:ValidateTemplate is not allowed", opts.StackName)
+	} else if err != nil {
+		return errors.Wrap(err, "validating template")
+	}
+
+	if len(findings) > 0 {
+		b.term.Print(ui.RenderFindings(opts.Template, findings))
+	}
+
+	if errs := validate.CountErrors(findings); errs > 0 {
+		return errors.Wrapf(ErrInvalidTemplate, "%d errors found in stack %s's template", errs, opts.StackName)
+	}
+
+	return nil
+}
@@ -0,0 +1,44 @@
+package deployer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/smithy-go"
+	"github.com/pkg/errors"
+)
+
+func TestValidateTemplate(t *testing.T) {
+	const valid = "Resources:\n  Queue:\n    Type: AWS::SQS::Queue\n"
+	const invalid = "Resources:\n  Queue:\n    Type: AWS::SQS::Queue\n    Properties:\n      QueueName: !Ref Missing\n"
+
+	accessDenied := &smithy.GenericAPIError{Code: "AccessDenied", Message: "User is not authorized to perform: cloudformation:ValidateTemplate"}
+	rejected := &smithy.GenericAPIError{Code: "ValidationError", Message: "Template format error"}
+
+	for _, tc := range []struct {
+		name        string
+		template    string
+		validateErr error
+		invalid     bool
+	}{
+		{name: "valid", template: valid},
+		{name: "rejected by CloudFormation", template: valid, validateErr: rejected, invalid: true},
+		{name: "local error", template: invalid, invalid: true},
+		// Without permission to call ValidateTemplate, the local checks still apply
+		{name: "access denied", template: valid, validateErr: accessDenied},
+		{name: "access denied with local error", template: invalid, validateErr: accessDenied, invalid: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fake := &fakeCloudFormation{validateErr: tc.validateErr}
+
+			err := fake.deployer().validateTemplate(context.Background(), DeployOpts{StackName: "app", Template: tc.template})
+
+			if tc.invalid && errors.Cause(err) != ErrInvalidTemplate {
+				t.Errorf("expected an error caused by ErrInvalidTemplate, got %v", err)
+			}
+			if !tc.invalid && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package deployer
+
+import (
+	"context"
+
+	"github.com/common-fate/clio"
+	"github.com/common-fate/cloudform/ui"
+)
+
+type WatchOpts struct {
+	// StackNames are the stacks to watch
+	StackNames []string
+	// Follow keeps watching the stacks after their current operations
+	// have finished, rendering each new operation until ctx is cancelled
+	Follow bool
+}
+
+// Watch renders live progress for existing stacks, such as stacks which are being
+// updated by a pipeline. It returns once every stack has settled, immediately if they
+// already have. In follow mode it returns when ctx is cancelled, without an error.
+func (b *Deployer) Watch(ctx context.Context, opts WatchOpts) ([]ui.WatchResult, error) {
+	onSettle := b.printResult
+	if len(opts.StackNames) > 1 || opts.Follow {
+		onSettle = func(res ui.WatchResult) {
+			clio.Infof("Final status of %s: %s", res.StackName, ui.ColouriseStatus(res.Status))
+			b.printMessages(res)
+		}
+	}
+
+	results, err := b.uiClient.WatchStacks(ctx, opts.StackNames, ui.WatchOpts{
+		Follow:   opts.Follow,
+		OnSettle: onSettle,
+	})
+	if err != nil && opts.Follow && ctx.Err() != nil {
+		return results, nil
+	}
+
+	return results, err
+}
@@ -0,0 +1,251 @@
+package estimate
+
+import (
+	"sort"
+	"time"
+
+	"github.com/aws-cloudformation/rain/cft"
+	"github.com/aws-cloudformation/rain/cft/graph"
+	"github.com/aws-cloudformation/rain/cft/parse"
+)
+
+// DefaultDuration is how long a resource is expected to take
+// when there are no recorded durations for its type
+const DefaultDuration = 30 * time.Second
+
+// State is how far a resource has got through the operation
+type State int
+
+const (
+	Pending State = iota
+	Running
+	Done
+)
+
+// Resource is a resource which is part of the operation being estimated
+type Resource struct {
+	LogicalID string
+	Type      string
+	// Action is the lower case operation being applied to the resource, such as "create"
+	Action string
+	State  State
+	// Since is when the resource started changing. It is only used for running resources.
+	Since time.Time
+}
+
+// Estimate is the predicted progress of an operation
+type Estimate struct {
+	// Done is the number of resources which have finished
+	Done int
+	// Total is the number of resources taking part in the operation
+	Total int
+	// Percent is how much of the operation is complete, from 0 to 100,
+	// weighting each resource by how long it is expected to take
+	Percent float64
+	// Remaining is how much longer the operation is expected to take
+	Remaining time.Duration
+	// Known is true if there are recorded durations for any of the resources.
+	// If it is false, Remaining is a guess based on DefaultDuration.
+	Known bool
+}
+
+// Estimator predicts how long operations will take from recorded resource durations
+type Estimator struct {
+	store Store
+}
+
+// New creates an Estimator which reads durations from store
+func New(store Store) *Estimator {
+	return &Estimator{store: store}
+}
+
+// expected returns how long a resource is expected to take, and whether
+// this is based on recorded durations. The median is used so that a single
+// slow deployment doesn't skew the estimate.
+func (e *Estimator) expected(r Resource) (time.Duration, bool) {
+	if e.store == nil {
+		return DefaultDuration, false
+	}
+
+	durations, err := e.store.Durations(r.Type, r.Action)
+	if err != nil || len(durations) == 0 {
+		return DefaultDuration, false
+	}
+
+	sort.Slice(durations, func(i, j int) bool {
+		return durations[i] < durations[j]
+	})
+
+	mid := len(durations) / 2
+	if len(durations)%2 == 0 {
+		return (durations[mid-1] + durations[mid]) / 2, true
+	}
+	return durations[mid], true
+}
+
+// Estimate predicts the progress of an operation at now. deps maps each resource's
+// logical ID to the logical IDs it depends on, as returned by Dependencies.
+//
+// CloudFormation changes resources as soon as their dependencies have finished,
+// so the time remaining is the longest chain of unfinished resources.
+func (e *Estimator) Estimate(resources []Resource, deps map[string][]string, now time.Time) Estimate {
+	est := Estimate{Total: len(resources)}
+
+	byID := make(map[string]Resource, len(resources))
+	expected := make(map[string]time.Duration, len(resources))
+
+	var total, done time.Duration
+	for _, r := range resources {
+		d, known := e.expected(r)
+		if known {
+			est.Known = true
+		}
+
+		byID[r.LogicalID] = r
+		expected[r.LogicalID] = d
+		total += d
+
+		switch r.State {
+		case Done:
+			est.Done++
+			done += d
+		case Running:
+			// Count the time already spent, up to the expected duration
+			if elapsed := now.Sub(r.Since); elapsed > 0 {
+				if elapsed > d {
+					elapsed = d
+				}
+				done += elapsed
+			}
+		}
+	}
+
+	if total > 0 {
+		est.Percent = 100 * float64(done) / float64(total)
+	}
+
+	finish := make(map[string]time.Time, len(resources))
+	visiting := make(map[string]bool)
+
+	var finishTime func(id string) time.Time
+	finishTime = func(id string) time.Time {
+		if t, ok := finish[id]; ok {
+			return t
+		}
+
+		r, ok := byID[id]
+		if !ok || r.State == Done || visiting[id] {
+			// Resources outside the operation, finished resources and
+			// dependency cycles don't hold anything up
+			return now
+		}
+
+		visiting[id] = true
+		defer delete(visiting, id)
+
+		var end time.Time
+		if r.State == Running {
+			end = r.Since.Add(expected[id])
+		} else {
+			start := now
+			for _, dep := range deps[id] {
+				if t := finishTime(dep); t.After(start) {
+					start = t
+				}
+			}
+			end = start.Add(expected[id])
+		}
+
+		// A resource which is taking longer than usual could finish at any moment
+		if end.Before(now) {
+			end = now
+		}
+
+		finish[id] = end
+		return end
+	}
+
+	last := now
+	for _, r := range resources {
+		if t := finishTime(r.LogicalID); t.After(last) {
+			last = t
+		}
+	}
+	est.Remaining = last.Sub(now)
+
+	return est
+}
+
+// Dependencies reads the dependencies between resources from a template.
+// The result maps each resource's logical ID to the logical IDs of the resources
+// it depends on, through Ref, Fn::GetAtt, Fn::Sub and DependsOn.
+func Dependencies(template string) (map[string][]string, error) {
+	t, err := parse.String(template)
+	if err != nil {
+		return nil, err
+	}
+
+	return TemplateDependencies(t), nil
+}
+
+// TemplateDependencies reads the dependencies between resources from a parsed template.
+// See Dependencies.
+func TemplateDependencies(t cft.Template) map[string][]string {
+	deps := make(map[string][]string)
+
+	// graph.New panics on templates, resources and outputs which aren't objects
+	var m map[string]interface{}
+	if t.Decode(&m) != nil {
+		return deps
+	}
+
+	resources, ok := m["Resources"].(map[string]interface{})
+	if !ok {
+		return deps
+	}
+
+	outputs, _ := m["Outputs"].(map[string]interface{})
+	for _, section := range []map[string]interface{}{resources, outputs} {
+		for _, entry := range section {
+			if _, ok := entry.(map[string]interface{}); !ok {
+				return deps
+			}
+		}
+	}
+
+	g := graph.New(t)
+
+	for name, res := range resources {
+		seen := make(map[string]bool)
+		add := func(dep string) {
+			if _, ok := resources[dep]; ok && dep != name && !seen[dep] {
+				seen[dep] = true
+				deps[name] = append(deps[name], dep)
+			}
+		}
+
+		for _, node := range g.Get(graph.Node{Type: "Resources", Name: name}) {
+			if node.Type == "Resources" {
+				add(node.Name)
+			}
+		}
+
+		// The graph only follows intrinsic functions
+		if props, ok := res.(map[string]interface{}); ok {
+			switch dependsOn := props["DependsOn"].(type) {
+			case string:
+				add(dependsOn)
+			case []interface{}:
+				for _, dep := range dependsOn {
+					if s, ok := dep.(string); ok {
+						add(s)
+					}
+				}
+			}
+		}
+
+		sort.Strings(deps[name])
+	}
+
+	return deps
+}
@@ -0,0 +1,119 @@
+package estimate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const testTemplate = `
+Resources:
+  Bucket:
+    Type: AWS::S3::Bucket
+  Role:
+    Type: AWS::IAM::Role
+  Function:
+    Type: AWS::Lambda::Function
+    Properties:
+      Role: !GetAtt Role.Arn
+      Environment:
+        Variables:
+          BUCKET: !Ref Bucket
+  Alarm:
+    Type: AWS::CloudWatch::Alarm
+    DependsOn: Function
+Outputs:
+  BucketName:
+    Value: !Ref Bucket
+`
+
+func TestDependencies(t *testing.T) {
+	deps, err := Dependencies(testTemplate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string][]string{
+		"Function": {"Bucket", "Role"},
+		"Alarm":    {"Function"},
+	}
+
+	if diff := cmp.Diff(expected, deps); diff != "" {
+		t.Error(diff)
+	}
+
+	// Templates which aren't objects have no dependencies
+	deps, err = Dependencies("https://example.com/template.yml")
+	if err != nil || len(deps) != 0 {
+		t.Errorf("unexpected dependencies %v %v", deps, err)
+	}
+}
+
+func TestEstimate(t *testing.T) {
+	store := NewMemoryStore()
+	for _, d := range []time.Duration{10 * time.Second, 20 * time.Second, 90 * time.Second} {
+		_ = store.Record("AWS::IAM::Role", "create", d)
+	}
+	_ = store.Record("AWS::Lambda::Function", "create", 40*time.Second)
+	_ = store.Record("AWS::CloudWatch::Alarm", "create", 5*time.Second)
+
+	deps, err := Dependencies(testTemplate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	resources := []Resource{
+		{LogicalID: "Bucket", Type: "AWS::S3::Bucket", Action: "create", State: Done},
+		{LogicalID: "Role", Type: "AWS::IAM::Role", Action: "create", State: Running, Since: now.Add(-5 * time.Second)},
+		{LogicalID: "Function", Type: "AWS::Lambda::Function", Action: "create"},
+		{LogicalID: "Alarm", Type: "AWS::CloudWatch::Alarm", Action: "create"},
+	}
+
+	est := New(store).Estimate(resources, deps, now)
+
+	// The role is expected to take 20s and has been running for 5s,
+	// then the function and alarm are created one after the other
+	if want := 15*time.Second + 40*time.Second + 5*time.Second; est.Remaining != want {
+		t.Errorf("expected %s remaining, got %s", want, est.Remaining)
+	}
+	if est.Done != 1 || est.Total != 4 || !est.Known {
+		t.Errorf("unexpected estimate %+v", est)
+	}
+
+	// The bucket has no history so it is weighted by DefaultDuration
+	total := DefaultDuration + 20*time.Second + 40*time.Second + 5*time.Second
+	if want := 100 * float64(DefaultDuration+5*time.Second) / float64(total); est.Percent != want {
+		t.Errorf("expected %.1f%% complete, got %.1f%%", want, est.Percent)
+	}
+}
+
+func TestEstimateOverdue(t *testing.T) {
+	now := time.Now()
+	resources := []Resource{
+		{LogicalID: "Role", Type: "AWS::IAM::Role", Action: "create", State: Running, Since: now.Add(-time.Hour)},
+	}
+
+	est := New(NewMemoryStore()).Estimate(resources, nil, now)
+	if est.Remaining != 0 || est.Known {
+		t.Errorf("unexpected estimate %+v", est)
+	}
+	if est.Percent != 100 {
+		t.Errorf("expected an overdue resource to count as complete, got %.1f%%", est.Percent)
+	}
+}
+
+func TestEstimateCycle(t *testing.T) {
+	now := time.Now()
+	resources := []Resource{
+		{LogicalID: "A", Type: "Test::A", Action: "create"},
+		{LogicalID: "B", Type: "Test::B", Action: "create"},
+	}
+	deps := map[string][]string{"A": {"B"}, "B": {"A"}}
+
+	est := New(nil).Estimate(resources, deps, now)
+	if est.Remaining != 2*DefaultDuration {
+		t.Errorf("expected %s remaining, got %s", 2*DefaultDuration, est.Remaining)
+	}
+}
@@ -0,0 +1,178 @@
+// Package estimate predicts how long a stack operation will take, using how long
+// each type of resource took in previous deployments.
+package estimate
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// maxSamples is the number of durations kept for each resource type and action
+const maxSamples = 20
+
+// Store records how long resources took to change.
+// Actions are lower case operations such as "create", "update" and "delete".
+type Store interface {
+	// Record adds how long a resource of the given type took for an action
+	Record(resourceType, action string, d time.Duration) error
+	// Durations returns the durations recorded for a resource type and action, oldest first
+	Durations(resourceType, action string) ([]time.Duration, error)
+}
+
+func storeKey(resourceType, action string) string {
+	return resourceType + "/" + action
+}
+
+func appendSample(samples []time.Duration, d time.Duration) []time.Duration {
+	samples = append(samples, d)
+	if len(samples) > maxSamples {
+		samples = samples[len(samples)-maxSamples:]
+	}
+	return samples
+}
+
+// MemoryStore is a Store which keeps durations in memory
+type MemoryStore struct {
+	mu      sync.Mutex
+	samples map[string][]time.Duration
+}
+
+// NewMemoryStore creates an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		samples: make(map[string][]time.Duration),
+	}
+}
+
+// Record adds how long a resource of the given type took for an action
+func (m *MemoryStore) Record(resourceType, action string, d time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := storeKey(resourceType, action)
+	m.samples[key] = appendSample(m.samples[key], d)
+
+	return nil
+}
+
+// Durations returns the durations recorded for a resource type and action, oldest first
+func (m *MemoryStore) Durations(resourceType, action string) ([]time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]time.Duration(nil), m.samples[storeKey(resourceType, action)]...), nil
+}
+
+// FileStore is a Store which keeps durations in a JSON file.
+// The file is read when the store is first used and rewritten after each Record.
+type FileStore struct {
+	path string
+
+	mu      sync.Mutex
+	loaded  bool
+	samples map[string][]time.Duration
+}
+
+// NewFileStore creates a FileStore which keeps durations at path.
+// The file and its directory are created when the first duration is recorded.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// DefaultFileStorePath returns the default location of the durations file,
+// in the user's cache directory
+func DefaultFileStorePath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "cloudform", "durations.json"), nil
+}
+
+// load reads the file if it hasn't been read yet. It must be called with f.mu held.
+func (f *FileStore) load() error {
+	if f.loaded {
+		return nil
+	}
+
+	f.samples = make(map[string][]time.Duration)
+
+	data, err := os.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		f.loaded = true
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(data, &f.samples)
+	if err != nil {
+		return err
+	}
+
+	f.loaded = true
+	return nil
+}
+
+// Record adds how long a resource of the given type took for an action
+func (f *FileStore) Record(resourceType, action string, d time.Duration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	err := f.load()
+	if err != nil {
+		return err
+	}
+
+	key := storeKey(resourceType, action)
+	f.samples[key] = appendSample(f.samples[key], d)
+
+	data, err := json.MarshalIndent(f.samples, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(f.path), 0755)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so that the store isn't corrupted by an interruption.
+	// Each write uses its own file so that other processes recording at the same time
+	// can't replace it while it's being written.
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), f.path)
+}
+
+// Durations returns the durations recorded for a resource type and action, oldest first
+func (f *FileStore) Durations(resourceType, action string) ([]time.Duration, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	err := f.load()
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]time.Duration(nil), f.samples[storeKey(resourceType, action)]...), nil
+}
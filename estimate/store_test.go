@@ -0,0 +1,37 @@
+package estimate
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache", "durations.json")
+
+	store := NewFileStore(path)
+	for i := 1; i <= maxSamples+5; i++ {
+		err := store.Record("AWS::S3::Bucket", "create", time.Duration(i)*time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A new store reads what the first one wrote
+	durations, err := NewFileStore(path).Durations("AWS::S3::Bucket", "create")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(durations) != maxSamples {
+		t.Fatalf("expected %d samples, got %d", maxSamples, len(durations))
+	}
+	if durations[0] != 6*time.Second {
+		t.Errorf("expected the oldest samples to be dropped, got %s first", durations[0])
+	}
+
+	durations, err = store.Durations("AWS::S3::Bucket", "delete")
+	if err != nil || len(durations) != 0 {
+		t.Errorf("expected no samples, got %v %v", durations, err)
+	}
+}
@@ -0,0 +1,280 @@
+// Package history keeps an audit log of deployments and deletions:
+// who changed which stack, when, with which parameters, and how it went.
+package history
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNotFound is returned by Store.Get if there is no record with the ID
+var ErrNotFound = errors.New("history record not found")
+
+// Operation is the kind of change a record describes
+type Operation string
+
+const (
+	OperationDeploy Operation = "deploy"
+	OperationDelete Operation = "delete"
+)
+
+// Result is how a run finished
+type Result string
+
+const (
+	// ResultSucceeded means the stack was changed as requested
+	ResultSucceeded Result = "succeeded"
+	// ResultFailed means the stack operation ran but did not succeed, for example because it was rolled back
+	ResultFailed Result = "failed"
+	// ResultSkipped means there was nothing to change
+	ResultSkipped Result = "skipped"
+	// ResultCancelled means the change was not approved
+	ResultCancelled Result = "cancelled"
+	// ResultError means the run stopped because of an error, such as a template which couldn't be deployed
+	ResultError Result = "error"
+)
+
+// Approver is who approved a change
+type Approver struct {
+	// User is the local user who ran the deployment
+	User string `json:"user,omitempty"`
+	// AWSIdentity is the ARN of the AWS identity the change was made with
+	AWSIdentity string `json:"awsIdentity,omitempty"`
+	// AutoApproved is set if the change was made without an interactive confirmation
+	AutoApproved bool `json:"autoApproved"`
+}
+
+// Change is a summary of the change to a single resource
+type Change struct {
+	Action       string `json:"action"`
+	LogicalID    string `json:"logicalId"`
+	ResourceType string `json:"resourceType"`
+	Replacement  string `json:"replacement,omitempty"`
+}
+
+// Record describes a single deployment or deletion
+type Record struct {
+	ID        string    `json:"id"`
+	Operation Operation `json:"operation"`
+	StackName string    `json:"stackName"`
+	StackID   string    `json:"stackId,omitempty"`
+	// TemplateHash is the hex encoded SHA256 hash of the deployed template
+	TemplateHash string `json:"templateHash,omitempty"`
+	// Parameters are the parameter values by key, with secrets redacted. See RedactParameters.
+	Parameters    map[string]string `json:"parameters,omitempty"`
+	ChangeSetName string            `json:"changeSetName,omitempty"`
+	Changes       []Change          `json:"changes,omitempty"`
+	Approver      Approver          `json:"approver"`
+	Result        Result            `json:"result"`
+	// FinalStatus is the status of the stack when the run finished
+	FinalStatus string        `json:"finalStatus,omitempty"`
+	StartedAt   time.Time     `json:"startedAt"`
+	FinishedAt  time.Time     `json:"finishedAt"`
+	Duration    time.Duration `json:"duration"`
+	// Failures are the failure messages of the stack's resources
+	Failures []string `json:"failures,omitempty"`
+	// Error is the error the run returned, if any
+	Error string `json:"error,omitempty"`
+}
+
+// NewRecord creates a record for a run which started at startedAt, with a unique ID
+func NewRecord(op Operation, stackName string, startedAt time.Time) Record {
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+
+	return Record{
+		ID:        fmt.Sprintf("%s-%s", startedAt.UTC().Format("20060102T150405Z"), hex.EncodeToString(suffix)),
+		Operation: op,
+		StackName: stackName,
+		StartedAt: startedAt,
+	}
+}
+
+// Query filters the records returned by Store.List
+type Query struct {
+	// StackName only returns records for the stack, if set
+	StackName string
+	// Operation only returns records of the operation, if set
+	Operation Operation
+	// Since only returns records of runs which started at or after the time, if set
+	Since time.Time
+	// Limit is the maximum number of records to return. Zero means no limit.
+	Limit int
+}
+
+// matches returns true if the record passes the query's filters
+func (q Query) matches(r Record) bool {
+	if q.StackName != "" && r.StackName != q.StackName {
+		return false
+	}
+	if q.Operation != "" && r.Operation != q.Operation {
+		return false
+	}
+	if !q.Since.IsZero() && r.StartedAt.Before(q.Since) {
+		return false
+	}
+	return true
+}
+
+// apply filters records and sorts them with the most recent first
+func (q Query) apply(records []Record) []Record {
+	out := make([]Record, 0)
+	for _, r := range records {
+		if q.matches(r) {
+			out = append(out, r)
+		}
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].StartedAt.After(out[j].StartedAt)
+	})
+
+	if q.Limit > 0 && len(out) > q.Limit {
+		out = out[:q.Limit]
+	}
+
+	return out
+}
+
+// Store saves and queries records
+type Store interface {
+	// Save adds a record
+	Save(ctx context.Context, r Record) error
+	// List returns the records matching q, with the most recent first
+	List(ctx context.Context, q Query) ([]Record, error)
+	// Get returns the record with the ID, or ErrNotFound
+	Get(ctx context.Context, id string) (Record, error)
+}
+
+// ParameterChange is a parameter which differs between two records
+type ParameterChange struct {
+	Key  string
+	From string
+	To   string
+	// Added and Removed are set if the parameter is only in one of the records
+	Added   bool
+	Removed bool
+}
+
+// Comparison describes what changed between two records
+type Comparison struct {
+	From Record
+	To   Record
+	// TemplateChanged is set if the templates have different hashes
+	TemplateChanged bool
+	// Parameters are the parameters which differ, sorted by key.
+	// Redacted values are compared as equal, as their real values aren't known.
+	Parameters []ParameterChange
+	// DurationChange is how much longer the second run took
+	DurationChange time.Duration
+}
+
+// Compare returns what changed between two records
+func Compare(from, to Record) Comparison {
+	c := Comparison{
+		From:            from,
+		To:              to,
+		TemplateChanged: from.TemplateHash != to.TemplateHash,
+		DurationChange:  to.Duration - from.Duration,
+	}
+
+	keys := make(map[string]bool)
+	for k := range from.Parameters {
+		keys[k] = true
+	}
+	for k := range to.Parameters {
+		keys[k] = true
+	}
+
+	for k := range keys {
+		was, inFrom := from.Parameters[k]
+		is, inTo := to.Parameters[k]
+
+		switch {
+		case !inFrom:
+			c.Parameters = append(c.Parameters, ParameterChange{Key: k, To: is, Added: true})
+		case !inTo:
+			c.Parameters = append(c.Parameters, ParameterChange{Key: k, From: was, Removed: true})
+		case was != is:
+			c.Parameters = append(c.Parameters, ParameterChange{Key: k, From: was, To: is})
+		}
+	}
+
+	sort.Slice(c.Parameters, func(i, j int) bool {
+		return c.Parameters[i].Key < c.Parameters[j].Key
+	})
+
+	return c
+}
+
+// CompareByID fetches two records from a store and compares them
+func CompareByID(ctx context.Context, s Store, fromID, toID string) (Comparison, error) {
+	from, err := s.Get(ctx, fromID)
+	if err != nil {
+		return Comparison{}, fmt.Errorf("getting record %s: %w", fromID, err)
+	}
+
+	to, err := s.Get(ctx, toID)
+	if err != nil {
+		return Comparison{}, fmt.Errorf("getting record %s: %w", toID, err)
+	}
+
+	return Compare(from, to), nil
+}
+
+// String renders the comparison as text
+func (c Comparison) String() string {
+	out := strings.Builder{}
+
+	out.WriteString(fmt.Sprintf("%s (%s, %s) -> %s (%s, %s)\n",
+		c.From.ID, c.From.Result, c.From.StartedAt.Format(time.RFC3339),
+		c.To.ID, c.To.Result, c.To.StartedAt.Format(time.RFC3339),
+	))
+
+	if c.TemplateChanged {
+		out.WriteString(fmt.Sprintf("  template: %s -> %s\n", shortHash(c.From.TemplateHash), shortHash(c.To.TemplateHash)))
+	} else {
+		out.WriteString("  template: unchanged\n")
+	}
+
+	if len(c.Parameters) == 0 {
+		out.WriteString("  parameters: unchanged\n")
+	} else {
+		out.WriteString("  parameters:\n")
+		for _, p := range c.Parameters {
+			switch {
+			case p.Added:
+				out.WriteString(fmt.Sprintf("    + %s: %s\n", p.Key, p.To))
+			case p.Removed:
+				out.WriteString(fmt.Sprintf("    - %s: %s\n", p.Key, p.From))
+			default:
+				out.WriteString(fmt.Sprintf("    ~ %s: %s -> %s\n", p.Key, p.From, p.To))
+			}
+		}
+	}
+
+	if c.From.FinalStatus != c.To.FinalStatus {
+		out.WriteString(fmt.Sprintf("  final status: %s -> %s\n", c.From.FinalStatus, c.To.FinalStatus))
+	}
+
+	out.WriteString(fmt.Sprintf("  duration: %s -> %s\n", c.From.Duration.Round(time.Second), c.To.Duration.Round(time.Second)))
+
+	return strings.TrimSuffix(out.String(), "\n")
+}
+
+func shortHash(hash string) string {
+	if hash == "" {
+		return "(none)"
+	}
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
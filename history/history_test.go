@@ -0,0 +1,179 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func testRecords(start time.Time) []Record {
+	return []Record{
+		{ID: "1", Operation: OperationDeploy, StackName: "app", StartedAt: start},
+		{ID: "2", Operation: OperationDeploy, StackName: "db", StartedAt: start.Add(time.Minute)},
+		{ID: "3", Operation: OperationDeploy, StackName: "app", StartedAt: start.Add(2 * time.Minute)},
+		{ID: "4", Operation: OperationDelete, StackName: "app", StartedAt: start.Add(3 * time.Minute)},
+	}
+}
+
+func ids(records []Record) []string {
+	out := make([]string, len(records))
+	for i, r := range records {
+		out[i] = r.ID
+	}
+	return out
+}
+
+func TestStores(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	for name, store := range map[string]Store{
+		"memory": NewMemoryStore(),
+		"file":   NewFileStore(filepath.Join(t.TempDir(), "cloudform", "history.jsonl")),
+	} {
+		for _, r := range testRecords(start) {
+			err := store.Save(ctx, r)
+			if err != nil {
+				t.Fatalf("%s: %s", name, err)
+			}
+		}
+
+		for _, tc := range []struct {
+			query    Query
+			expected []string
+		}{
+			{Query{}, []string{"4", "3", "2", "1"}},
+			{Query{StackName: "app"}, []string{"4", "3", "1"}},
+			{Query{StackName: "app", Operation: OperationDeploy}, []string{"3", "1"}},
+			{Query{Since: start.Add(time.Minute)}, []string{"4", "3", "2"}},
+			{Query{Limit: 2}, []string{"4", "3"}},
+		} {
+			records, err := store.List(ctx, tc.query)
+			if err != nil {
+				t.Fatalf("%s: %s", name, err)
+			}
+			if diff := cmp.Diff(tc.expected, ids(records)); diff != "" {
+				t.Errorf("%s %+v: %s", name, tc.query, diff)
+			}
+		}
+
+		r, err := store.Get(ctx, "2")
+		if err != nil || r.StackName != "db" {
+			t.Errorf("%s: unexpected record %+v %v", name, r, err)
+		}
+
+		_, err = store.Get(ctx, "missing")
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: expected ErrNotFound, got %v", name, err)
+		}
+	}
+}
+
+func TestFileStoreConcurrentSaves(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+
+	// Each store stands in for a separate process saving to the same file
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := NewFileStore(path).Save(ctx, Record{ID: fmt.Sprint(i), StackName: "app"})
+			if err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	records, err := NewFileStore(path).List(ctx, Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 20 {
+		t.Errorf("expected 20 records, got %d", len(records))
+	}
+
+	// A record which was only partly written is skipped, along with whatever follows it on its line
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = f.WriteString(`{"id":"partial`)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = NewFileStore(path).Save(ctx, Record{ID: "after", StackName: "app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = NewFileStore(path).Save(ctx, Record{ID: "last", StackName: "app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records, err = NewFileStore(path).List(ctx, Query{})
+	if err != nil || len(records) != 21 {
+		t.Errorf("expected the partial record to be skipped, got %d records and %v", len(records), err)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	from := Record{
+		ID:           "1",
+		TemplateHash: "aaaaaaaaaaaaaaaa",
+		Parameters:   map[string]string{"Env": "dev", "Size": "small", "Password": Redacted, "Old": "x"},
+		FinalStatus:  "UPDATE_COMPLETE",
+		Duration:     time.Minute,
+	}
+	to := Record{
+		ID:           "2",
+		TemplateHash: "bbbbbbbbbbbbbbbb",
+		Parameters:   map[string]string{"Env": "dev", "Size": "large", "Password": Redacted, "New": "y"},
+		FinalStatus:  "UPDATE_ROLLBACK_COMPLETE",
+		Duration:     3 * time.Minute,
+	}
+
+	c := Compare(from, to)
+
+	if !c.TemplateChanged || c.DurationChange != 2*time.Minute {
+		t.Errorf("unexpected comparison %+v", c)
+	}
+
+	expected := []ParameterChange{
+		{Key: "New", To: "y", Added: true},
+		{Key: "Old", From: "x", Removed: true},
+		{Key: "Size", From: "small", To: "large"},
+	}
+	if diff := cmp.Diff(expected, c.Parameters); diff != "" {
+		t.Error(diff)
+	}
+
+	ctx := context.Background()
+	store := NewMemoryStore()
+	_ = store.Save(ctx, from)
+	_ = store.Save(ctx, to)
+
+	byID, err := CompareByID(ctx, store, "1", "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(c, byID); diff != "" {
+		t.Error(diff)
+	}
+
+	_, err = CompareByID(ctx, store, "1", "missing")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
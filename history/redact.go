@@ -0,0 +1,71 @@
+package history
+
+import (
+	"regexp"
+
+	"github.com/aws-cloudformation/rain/cft/parse"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/smithy-go/ptr"
+)
+
+// Redacted replaces the values of secret parameters
+const Redacted = "****"
+
+// PreviousValue is recorded for parameters which keep their previous value
+const PreviousValue = "(previous value)"
+
+// secretName matches parameter names which are likely to hold secrets
+var secretName = regexp.MustCompile(`(?i)(password|passwd|secret|token|api_?key|private_?key|credential)`)
+
+// RedactParameters returns parameter values by key. The values of parameters
+// marked NoEcho in the template, and of parameters whose names suggest they
+// hold secrets, are replaced with Redacted. template may be a URL, in which
+// case only parameter names are checked.
+func RedactParameters(params []types.Parameter, template string) map[string]string {
+	noEcho := noEchoParameters(template)
+
+	out := make(map[string]string, len(params))
+	for _, p := range params {
+		key := ptr.ToString(p.ParameterKey)
+
+		switch {
+		case p.UsePreviousValue != nil && *p.UsePreviousValue:
+			out[key] = PreviousValue
+		case noEcho[key] || secretName.MatchString(key):
+			out[key] = Redacted
+		default:
+			out[key] = ptr.ToString(p.ParameterValue)
+		}
+	}
+
+	return out
+}
+
+// noEchoParameters returns the names of the parameters in a template which are marked NoEcho
+func noEchoParameters(template string) map[string]bool {
+	out := make(map[string]bool)
+
+	t, err := parse.String(template)
+	if err != nil {
+		return out
+	}
+
+	// Templates which aren't objects, such as URLs, can't be read
+	var m map[string]interface{}
+	if t.Decode(&m) != nil {
+		return out
+	}
+
+	params, _ := m["Parameters"].(map[string]interface{})
+	for name, param := range params {
+		param, _ := param.(map[string]interface{})
+		switch noEcho := param["NoEcho"].(type) {
+		case bool:
+			out[name] = noEcho
+		case string:
+			out[name] = noEcho == "true"
+		}
+	}
+
+	return out
+}
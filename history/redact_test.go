@@ -0,0 +1,52 @@
+package history
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/smithy-go/ptr"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRedactParameters(t *testing.T) {
+	template := `
+Parameters:
+  Env:
+    Type: String
+  AdminPin:
+    Type: String
+    NoEcho: true
+  Webhook:
+    Type: String
+    NoEcho: "true"
+`
+
+	params := []types.Parameter{
+		{ParameterKey: ptr.String("Env"), ParameterValue: ptr.String("prod")},
+		{ParameterKey: ptr.String("AdminPin"), ParameterValue: ptr.String("1234")},
+		{ParameterKey: ptr.String("Webhook"), ParameterValue: ptr.String("https://example.com/hook")},
+		{ParameterKey: ptr.String("DatabasePassword"), ParameterValue: ptr.String("hunter2")},
+		{ParameterKey: ptr.String("GithubToken"), ParameterValue: ptr.String("ghp_abc")},
+		{ParameterKey: ptr.String("Size"), UsePreviousValue: ptr.Bool(true)},
+	}
+
+	expected := map[string]string{
+		"Env":              "prod",
+		"AdminPin":         Redacted,
+		"Webhook":          Redacted,
+		"DatabasePassword": Redacted,
+		"GithubToken":      Redacted,
+		"Size":             PreviousValue,
+	}
+
+	if diff := cmp.Diff(expected, RedactParameters(params, template)); diff != "" {
+		t.Error(diff)
+	}
+
+	// Templates given as URLs can't be read, so only parameter names are checked
+	expected["AdminPin"] = "1234"
+	expected["Webhook"] = "https://example.com/hook"
+	if diff := cmp.Diff(expected, RedactParameters(params, "https://example.com/template.yml")); diff != "" {
+		t.Error(diff)
+	}
+}
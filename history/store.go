@@ -0,0 +1,164 @@
+package history
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// MemoryStore is a Store which keeps records in memory
+type MemoryStore struct {
+	mu      sync.Mutex
+	records []Record
+}
+
+// NewMemoryStore creates an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// Save adds a record
+func (m *MemoryStore) Save(ctx context.Context, r Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.records = append(m.records, r)
+	return nil
+}
+
+// List returns the records matching q, with the most recent first
+func (m *MemoryStore) List(ctx context.Context, q Query) ([]Record, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return q.apply(m.records), nil
+}
+
+// Get returns the record with the ID, or ErrNotFound
+func (m *MemoryStore) Get(ctx context.Context, id string) (Record, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return find(m.records, id)
+}
+
+func find(records []Record, id string) (Record, error) {
+	for _, r := range records {
+		if r.ID == id {
+			return r, nil
+		}
+	}
+	return Record{}, ErrNotFound
+}
+
+// FileStore is a Store which keeps records in a file, one JSON record per line.
+// Records are appended to the file, so several processes can save records at once
+// without losing any. The file is read on every call so that records saved by
+// other processes are seen.
+type FileStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileStore creates a FileStore which keeps records at path.
+// The file and its directory are created when the first record is saved.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// DefaultFileStorePath returns the default location of the history file,
+// in the user's config directory
+func DefaultFileStorePath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "cloudform", "history.jsonl"), nil
+}
+
+func (f *FileStore) read() ([]Record, error) {
+	data, err := os.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Lines which can't be decoded, such as a record whose write was interrupted,
+	// are skipped so that they don't hide the rest of the history
+	var records []Record
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var r Record
+		if json.Unmarshal(line, &r) == nil {
+			records = append(records, r)
+		}
+	}
+
+	return records, nil
+}
+
+// Save adds a record by appending it to the file
+func (f *FileStore) Save(ctx context.Context, r Record) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	err = os.MkdirAll(filepath.Dir(f.path), 0755)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	// The record is written with a single call so that records appended
+	// by other processes at the same time aren't interleaved with it
+	_, err = file.Write(append(data, '\n'))
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// List returns the records matching q, with the most recent first
+func (f *FileStore) List(ctx context.Context, q Query) ([]Record, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	records, err := f.read()
+	if err != nil {
+		return nil, err
+	}
+
+	return q.apply(records), nil
+}
+
+// Get returns the record with the ID, or ErrNotFound
+func (f *FileStore) Get(ctx context.Context, id string) (Record, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	records, err := f.read()
+	if err != nil {
+		return Record{}, err
+	}
+
+	return find(records, id)
+}
@@ -0,0 +1,177 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrConditionFailed is returned by a Table when the condition on a write doesn't hold
+var ErrConditionFailed = errors.New("condition failed")
+
+// Item is a lock as stored in a Table
+type Item struct {
+	Key    string
+	Holder Holder
+	// Version is incremented on every write, so that writes can be conditional
+	// on the item not having changed since it was read
+	Version int64
+}
+
+// Table is a key value store with conditional writes, such as a DynamoDB table.
+//
+// A DynamoDB implementation stores items with Key as the partition key and
+// makes each write conditional: "attribute_not_exists(#key)" when expectVersion
+// is zero, or "#version = :expectVersion" otherwise. A failed condition
+// (ConditionalCheckFailedException) is returned as ErrConditionFailed.
+// Holder.ExpiresAt can also be stored as a number for DynamoDB's TTL feature
+// to remove expired locks.
+type Table interface {
+	// Get returns the item with the key, or nil if there isn't one
+	Get(ctx context.Context, key string) (*Item, error)
+	// Put writes item. If expectVersion is zero there must be no item with the key,
+	// otherwise the existing item must have the version.
+	Put(ctx context.Context, item Item, expectVersion int64) error
+	// Delete removes the item with the key if it has the version
+	Delete(ctx context.Context, key string, expectVersion int64) error
+}
+
+// DynamoLocker keeps locks in a Table, so that deployments on different machines
+// are stopped from running at once
+type DynamoLocker struct {
+	table Table
+}
+
+// NewDynamoLocker creates a DynamoLocker which keeps locks in table
+func NewDynamoLocker(table Table) *DynamoLocker {
+	return &DynamoLocker{table: table}
+}
+
+// TryAcquire takes the lock for key on behalf of holder, if it is free or has expired
+func (d *DynamoLocker) TryAcquire(ctx context.Context, key string, holder Holder) error {
+	item, err := d.table.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	var version int64
+	if item != nil {
+		if !item.Holder.Expired(time.Now()) && item.Holder.ID != holder.ID {
+			return &LockedError{Key: key, Holder: item.Holder}
+		}
+		version = item.Version
+	}
+
+	err = d.table.Put(ctx, Item{Key: key, Holder: holder, Version: version + 1}, version)
+	if errors.Is(err, ErrConditionFailed) {
+		// Someone else took the lock since we read it
+		current, getErr := d.table.Get(ctx, key)
+		if getErr != nil {
+			return getErr
+		}
+		if current == nil {
+			return &LockedError{Key: key}
+		}
+		return &LockedError{Key: key, Holder: current.Holder}
+	}
+
+	return err
+}
+
+// Refresh extends a lock held by holder to holder.ExpiresAt
+func (d *DynamoLocker) Refresh(ctx context.Context, key string, holder Holder) error {
+	item, err := d.table.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	if item == nil || item.Holder.ID != holder.ID {
+		return ErrNotHeld
+	}
+
+	err = d.table.Put(ctx, Item{Key: key, Holder: holder, Version: item.Version + 1}, item.Version)
+	if errors.Is(err, ErrConditionFailed) {
+		return ErrNotHeld
+	}
+
+	return err
+}
+
+// Release frees a lock held by holderID
+func (d *DynamoLocker) Release(ctx context.Context, key string, holderID string) error {
+	item, err := d.table.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	if item == nil || item.Holder.ID != holderID {
+		return ErrNotHeld
+	}
+
+	err = d.table.Delete(ctx, key, item.Version)
+	if errors.Is(err, ErrConditionFailed) {
+		return ErrNotHeld
+	}
+
+	return err
+}
+
+// MemoryTable is a Table which keeps items in memory. It can be shared by
+// several DynamoLockers to stand in for a DynamoDB table in tests.
+type MemoryTable struct {
+	mu    sync.Mutex
+	items map[string]Item
+}
+
+// NewMemoryTable creates an empty MemoryTable
+func NewMemoryTable() *MemoryTable {
+	return &MemoryTable{
+		items: make(map[string]Item),
+	}
+}
+
+// Get returns the item with the key, or nil if there isn't one
+func (m *MemoryTable) Get(ctx context.Context, key string) (*Item, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	item, ok := m.items[key]
+	if !ok {
+		return nil, nil
+	}
+	return &item, nil
+}
+
+// Put writes item if the existing item has expectVersion, or if expectVersion is zero and there is no item
+func (m *MemoryTable) Put(ctx context.Context, item Item, expectVersion int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.matches(item.Key, expectVersion) {
+		return ErrConditionFailed
+	}
+
+	m.items[item.Key] = item
+	return nil
+}
+
+// Delete removes the item with the key if it has the version
+func (m *MemoryTable) Delete(ctx context.Context, key string, expectVersion int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.matches(key, expectVersion) {
+		return ErrConditionFailed
+	}
+
+	delete(m.items, key)
+	return nil
+}
+
+// matches checks a write's condition. It must be called with m.mu held.
+func (m *MemoryTable) matches(key string, expectVersion int64) bool {
+	existing, ok := m.items[key]
+	if expectVersion == 0 {
+		return !ok
+	}
+	return ok && existing.Version == expectVersion
+}
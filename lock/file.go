@@ -0,0 +1,152 @@
+package lock
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileLocker keeps locks as files in a directory, so it only stops deployments
+// on the same machine from running at once. Each lock file holds its Holder as JSON.
+type FileLocker struct {
+	dir string
+}
+
+// NewFileLocker creates a FileLocker which keeps lock files in dir.
+// The directory is created when the first lock is taken.
+func NewFileLocker(dir string) *FileLocker {
+	return &FileLocker{dir: dir}
+}
+
+// DefaultFileLockerDir returns the default location of lock files, in the user's cache directory
+func DefaultFileLockerDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "cloudform", "locks"), nil
+}
+
+func (f *FileLocker) path(key string) string {
+	return filepath.Join(f.dir, url.PathEscape(key)+".lock")
+}
+
+// read returns the holder of a lock, or nil if the lock is free
+func (f *FileLocker) read(key string) (*Holder, error) {
+	data, err := os.ReadFile(f.path(key))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var h Holder
+	err = json.Unmarshal(data, &h)
+	if err != nil {
+		return nil, err
+	}
+
+	return &h, nil
+}
+
+// TryAcquire takes the lock for key on behalf of holder, if it is free or has expired
+func (f *FileLocker) TryAcquire(ctx context.Context, key string, holder Holder) error {
+	err := os.MkdirAll(f.dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(holder)
+	if err != nil {
+		return err
+	}
+
+	// Write the holder to a temporary file and then link it into place, which fails
+	// if the lock file exists. This way the lock file is never seen partly written.
+	tmp := f.path(key) + "." + holder.ID
+	err = os.WriteFile(tmp, data, 0644)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+
+	// Try twice, in case an expired lock needs to be removed first
+	for attempt := 0; attempt < 2; attempt++ {
+		err = os.Link(tmp, f.path(key))
+		if err == nil {
+			return nil
+		}
+		if !os.IsExist(err) {
+			return err
+		}
+
+		current, err := f.read(key)
+		if err != nil {
+			return err
+		}
+		if current != nil && !current.Expired(time.Now()) {
+			return &LockedError{Key: key, Holder: *current}
+		}
+
+		// There is a small window where two processes could both see the lock as
+		// expired and one removes the lock the other has just taken. Use DynamoLocker
+		// where this matters.
+		err = os.Remove(f.path(key))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	// Someone else took the expired lock first
+	current, err := f.read(key)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		current = &Holder{}
+	}
+	return &LockedError{Key: key, Holder: *current}
+}
+
+// Refresh extends a lock held by holder to holder.ExpiresAt
+func (f *FileLocker) Refresh(ctx context.Context, key string, holder Holder) error {
+	current, err := f.read(key)
+	if err != nil {
+		return err
+	}
+	if current == nil || current.ID != holder.ID {
+		return ErrNotHeld
+	}
+
+	data, err := json.Marshal(holder)
+	if err != nil {
+		return err
+	}
+
+	// Replace the file atomically so that it is never seen partly written
+	tmp := f.path(key) + "." + holder.ID
+	err = os.WriteFile(tmp, data, 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, f.path(key))
+}
+
+// Release frees a lock held by holderID
+func (f *FileLocker) Release(ctx context.Context, key string, holderID string) error {
+	current, err := f.read(key)
+	if err != nil {
+		return err
+	}
+	if current == nil || current.ID != holderID {
+		return ErrNotHeld
+	}
+
+	return os.Remove(f.path(key))
+}
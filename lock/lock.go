@@ -0,0 +1,251 @@
+// Package lock stops stacks from being changed by more than one deployment at a time.
+//
+// A Locker takes locks by key, usually the stack name. Locks expire after a TTL so that
+// a crashed deployment doesn't block the stack forever; Acquire keeps the lock
+// alive until it is released.
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"sync"
+	"time"
+)
+
+// ErrLocked is wrapped by the error returned when a lock is held by someone else.
+// Use errors.As with *LockedError to find the holder.
+var ErrLocked = errors.New("lock is held")
+
+// ErrNotHeld is returned when refreshing or releasing a lock which has expired
+// and been taken by someone else, or which has been removed
+var ErrNotHeld = errors.New("lock is not held")
+
+// Holder describes who holds a lock
+type Holder struct {
+	// ID is unique to each attempt to take a lock
+	ID   string `json:"id"`
+	User string `json:"user,omitempty"`
+	Host string `json:"host,omitempty"`
+	PID  int    `json:"pid,omitempty"`
+	// Description is optional extra detail, such as a CI job URL
+	Description string    `json:"description,omitempty"`
+	AcquiredAt  time.Time `json:"acquiredAt"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+}
+
+// NewHolder returns a holder for the current process with a new ID
+func NewHolder(description string) Holder {
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+
+	h := Holder{
+		ID:          hex.EncodeToString(id),
+		PID:         os.Getpid(),
+		Description: description,
+	}
+	if u, err := user.Current(); err == nil {
+		h.User = u.Username
+	}
+	if host, err := os.Hostname(); err == nil {
+		h.Host = host
+	}
+
+	return h
+}
+
+// Expired returns true if the lock has expired at now
+func (h Holder) Expired(now time.Time) bool {
+	return !now.Before(h.ExpiresAt)
+}
+
+func (h Holder) String() string {
+	s := fmt.Sprintf("%s@%s (pid %d)", h.User, h.Host, h.PID)
+	if h.Description != "" {
+		s += " " + h.Description
+	}
+	return s
+}
+
+// LockedError is returned when a lock is held by someone else
+type LockedError struct {
+	Key    string
+	Holder Holder
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%s is locked by %s since %s, until %s", e.Key, e.Holder,
+		e.Holder.AcquiredAt.Format(time.RFC3339), e.Holder.ExpiresAt.Format(time.RFC3339))
+}
+
+func (e *LockedError) Unwrap() error {
+	return ErrLocked
+}
+
+// Locker takes and releases locks. Implementations must be safe to use from several
+// processes at once, each with their own Locker.
+type Locker interface {
+	// TryAcquire takes the lock for key on behalf of holder, if it is free or has expired.
+	// holder.ExpiresAt is when the lock expires. If the lock is held by someone else,
+	// a *LockedError is returned.
+	TryAcquire(ctx context.Context, key string, holder Holder) error
+	// Refresh extends a lock held by holder to holder.ExpiresAt.
+	// ErrNotHeld is returned if holder no longer holds the lock.
+	Refresh(ctx context.Context, key string, holder Holder) error
+	// Release frees a lock held by holder.
+	// ErrNotHeld is returned if holder no longer holds the lock.
+	Release(ctx context.Context, key string, holderID string) error
+}
+
+// AcquireOpts configures Acquire
+type AcquireOpts struct {
+	// TTL is how long the lock lasts if it isn't refreshed. It defaults to five minutes.
+	// The lock is refreshed while it is held, so the TTL only matters if the holder dies.
+	TTL time.Duration
+	// Wait waits for the lock to be released, or to expire, rather than failing if it is held
+	Wait bool
+	// PollInterval is how often a held lock is checked while waiting. It defaults to five seconds.
+	PollInterval time.Duration
+	// Description is recorded in the holder, such as a CI job URL
+	Description string
+	// OnWait is called with the current holder each time the lock is found to be held while waiting
+	OnWait func(Holder)
+}
+
+// Lease is a lock which has been acquired. It is refreshed in the background until released.
+type Lease struct {
+	locker Locker
+	key    string
+	ttl    time.Duration
+
+	mu     sync.Mutex
+	holder Holder
+	err    error
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// Acquire takes the lock for key, refreshing it in the background until the lease is released.
+// If the lock is held by someone else, a *LockedError is returned unless opts.Wait is set,
+// in which case Acquire waits until the lock is free or ctx is cancelled.
+func Acquire(ctx context.Context, l Locker, key string, opts AcquireOpts) (*Lease, error) {
+	ttl := opts.TTL
+	if ttl == 0 {
+		ttl = 5 * time.Minute
+	}
+	interval := opts.PollInterval
+	if interval == 0 {
+		interval = 5 * time.Second
+	}
+
+	holder := NewHolder(opts.Description)
+
+	for {
+		holder.AcquiredAt = time.Now()
+		holder.ExpiresAt = holder.AcquiredAt.Add(ttl)
+
+		err := l.TryAcquire(ctx, key, holder)
+		if err == nil {
+			break
+		}
+
+		var locked *LockedError
+		if !opts.Wait || !errors.As(err, &locked) {
+			return nil, err
+		}
+
+		if opts.OnWait != nil {
+			opts.OnWait(locked.Holder)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+
+	lease := &Lease{
+		locker: l,
+		key:    key,
+		ttl:    ttl,
+		holder: holder,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	go lease.refresh()
+
+	return lease, nil
+}
+
+// refresh extends the lock a few times within each TTL until the lease is released
+func (l *Lease) refresh() {
+	defer close(l.done)
+
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+		}
+
+		l.mu.Lock()
+		holder := l.holder
+		holder.ExpiresAt = time.Now().Add(l.ttl)
+		l.mu.Unlock()
+
+		err := l.locker.Refresh(context.Background(), l.key, holder)
+
+		l.mu.Lock()
+		if err == nil {
+			l.holder = holder
+		} else if errors.Is(err, ErrNotHeld) {
+			// Someone else has the lock, so there's nothing more to refresh
+			l.err = err
+			l.mu.Unlock()
+			return
+		}
+		l.mu.Unlock()
+	}
+}
+
+// Holder returns who holds the lease's lock
+func (l *Lease) Holder() Holder {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.holder
+}
+
+// Err returns ErrNotHeld if the lock was lost while it was held, for example because
+// it couldn't be refreshed before it expired
+func (l *Lease) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.err
+}
+
+// Release stops refreshing the lock and frees it
+func (l *Lease) Release(ctx context.Context) error {
+	l.stopOnce.Do(func() {
+		close(l.stop)
+	})
+	<-l.done
+
+	if err := l.Err(); err != nil {
+		return err
+	}
+
+	return l.locker.Release(ctx, l.key, l.holder.ID)
+}
@@ -0,0 +1,165 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// lockerPairs returns two lockers for each implementation which share their locks,
+// as if they were on different machines
+func lockerPairs(t *testing.T) map[string][2]Locker {
+	dir := t.TempDir()
+	table := NewMemoryTable()
+
+	return map[string][2]Locker{
+		"file":   {NewFileLocker(dir), NewFileLocker(dir)},
+		"dynamo": {NewDynamoLocker(table), NewDynamoLocker(table)},
+	}
+}
+
+func TestLockers(t *testing.T) {
+	ctx := context.Background()
+
+	for name, lockers := range lockerPairs(t) {
+		a, b := lockers[0], lockers[1]
+
+		lease, err := Acquire(ctx, a, "us-east-1/app", AcquireOpts{Description: "job 1"})
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+
+		_, err = Acquire(ctx, b, "us-east-1/app", AcquireOpts{})
+		var locked *LockedError
+		if !errors.As(err, &locked) || !errors.Is(err, ErrLocked) {
+			t.Fatalf("%s: expected the lock to be held, got %v", name, err)
+		}
+		if locked.Holder.ID != lease.Holder().ID || locked.Holder.Description != "job 1" {
+			t.Errorf("%s: unexpected holder %+v", name, locked.Holder)
+		}
+
+		// Other keys are independent
+		other, err := Acquire(ctx, b, "us-east-1/db", AcquireOpts{})
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+
+		err = b.Release(ctx, "us-east-1/app", "someone-else")
+		if !errors.Is(err, ErrNotHeld) {
+			t.Errorf("%s: expected ErrNotHeld, got %v", name, err)
+		}
+
+		for _, l := range []*Lease{lease, other} {
+			err = l.Release(ctx)
+			if err != nil {
+				t.Fatalf("%s: %s", name, err)
+			}
+		}
+
+		lease, err = Acquire(ctx, b, "us-east-1/app", AcquireOpts{})
+		if err != nil {
+			t.Fatalf("%s: expected the lock to be free, got %s", name, err)
+		}
+		_ = lease.Release(ctx)
+	}
+}
+
+func TestLockExpires(t *testing.T) {
+	ctx := context.Background()
+
+	for name, lockers := range lockerPairs(t) {
+		a, b := lockers[0], lockers[1]
+
+		// A holder which died without releasing its lock
+		h := NewHolder("")
+		h.AcquiredAt = time.Now().Add(-time.Hour)
+		h.ExpiresAt = time.Now().Add(-time.Minute)
+		err := a.TryAcquire(ctx, "app", h)
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+
+		lease, err := Acquire(ctx, b, "app", AcquireOpts{})
+		if err != nil {
+			t.Fatalf("%s: expected the expired lock to be taken, got %s", name, err)
+		}
+
+		err = a.Refresh(ctx, "app", h)
+		if !errors.Is(err, ErrNotHeld) {
+			t.Errorf("%s: expected ErrNotHeld, got %v", name, err)
+		}
+
+		_ = lease.Release(ctx)
+	}
+}
+
+func TestLeaseRefresh(t *testing.T) {
+	ctx := context.Background()
+
+	for name, lockers := range lockerPairs(t) {
+		a, b := lockers[0], lockers[1]
+
+		lease, err := Acquire(ctx, a, "app", AcquireOpts{TTL: 90 * time.Millisecond})
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+
+		// The lease outlives its TTL because it is refreshed
+		time.Sleep(250 * time.Millisecond)
+
+		_, err = Acquire(ctx, b, "app", AcquireOpts{})
+		if !errors.Is(err, ErrLocked) {
+			t.Errorf("%s: expected the lock to still be held, got %v", name, err)
+		}
+
+		err = lease.Release(ctx)
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+	}
+}
+
+func TestAcquireWait(t *testing.T) {
+	ctx := context.Background()
+
+	for name, lockers := range lockerPairs(t) {
+		a, b := lockers[0], lockers[1]
+
+		lease, err := Acquire(ctx, a, "app", AcquireOpts{})
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+
+		waits := 0
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			_ = lease.Release(ctx)
+		}()
+
+		waiter, err := Acquire(ctx, b, "app", AcquireOpts{
+			Wait:         true,
+			PollInterval: 10 * time.Millisecond,
+			OnWait: func(h Holder) {
+				waits++
+			},
+		})
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		if waits == 0 {
+			t.Errorf("%s: expected OnWait to be called", name)
+		}
+		_ = waiter.Release(ctx)
+
+		// Waiting stops when the context is cancelled
+		lease, _ = Acquire(ctx, a, "app", AcquireOpts{})
+		cctx, cancel := context.WithTimeout(ctx, 30*time.Millisecond)
+		_, err = Acquire(cctx, b, "app", AcquireOpts{Wait: true, PollInterval: 10 * time.Millisecond})
+		cancel()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("%s: expected the wait to time out, got %v", name, err)
+		}
+		_ = lease.Release(ctx)
+	}
+}
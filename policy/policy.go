@@ -0,0 +1,215 @@
+// Package policy checks change sets against safety rules
+// before they are executed, so that destructive changes can be blocked.
+package policy
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Change is a single resource change being checked against a policy
+type Change struct {
+	// StackName is the name of the stack being deployed
+	StackName string
+	// Path is the logical ID of the resource prefixed by
+	// the logical IDs of any nested stacks, separated by "/"
+	Path string
+	// Action is the change set action: Add, Modify, Remove, Import or Dynamic
+	Action string
+	// ResourceType is the CloudFormation resource type
+	ResourceType string
+	// LogicalID is the logical ID of the resource
+	LogicalID string
+	// Replacement is CloudFormation's replacement flag: True, False or Conditional
+	Replacement string
+	// Tags are the tags of the resource
+	Tags map[string]string
+}
+
+// Rule forbids changes which match all of its criteria.
+// Criteria which are left empty match any change.
+type Rule struct {
+	// Name identifies the rule in violation messages
+	Name string `yaml:"name"`
+	// Description explains why the rule exists
+	Description string `yaml:"description"`
+	// Stacks are glob patterns matched against the stack name
+	Stacks []string `yaml:"stacks"`
+	// ResourceTypes are glob patterns matched against the resource type
+	ResourceTypes []string `yaml:"resourceTypes"`
+	// LogicalIDs are glob patterns matched against the resource's logical ID
+	LogicalIDs []string `yaml:"logicalIds"`
+	// Actions are the change set actions the rule applies to
+	Actions []string `yaml:"actions"`
+	// Replacement restricts the rule to changes which would (or may) replace the resource
+	Replacement bool `yaml:"replacement"`
+	// Tags restricts the rule to resources which have all of these tags
+	Tags map[string]string `yaml:"tags"`
+	// AllowOverride permits violations of the rule to be explicitly overridden
+	AllowOverride bool `yaml:"allowOverride"`
+	// Check is an optional condition for rules defined in code.
+	// It is called for changes which match the other criteria
+	// and should return true if the change violates the rule.
+	Check func(Change) bool `yaml:"-"`
+}
+
+// Violation is a change which broke a rule
+type Violation struct {
+	Rule   Rule
+	Change Change
+}
+
+func (v Violation) String() string {
+	msg := fmt.Sprintf("%s %s %s breaks rule '%s'", v.Change.Action, v.Change.ResourceType, v.Change.Path, v.Rule.Name)
+	if v.Rule.Description != "" {
+		msg += ": " + v.Rule.Description
+	}
+	return msg
+}
+
+// Policy is a set of rules
+type Policy struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// New creates a policy from rules
+func New(rules ...Rule) *Policy {
+	return &Policy{Rules: rules}
+}
+
+// Parse reads a YAML policy document
+func Parse(data []byte) (*Policy, error) {
+	var p Policy
+
+	err := yaml.Unmarshal(data, &p)
+	if err != nil {
+		return nil, fmt.Errorf("parsing policy: %w", err)
+	}
+
+	for i, r := range p.Rules {
+		if r.Name == "" {
+			return nil, fmt.Errorf("parsing policy: rule %d has no name", i+1)
+		}
+	}
+
+	return &p, nil
+}
+
+// Load reads a YAML policy file
+func Load(filename string) (*Policy, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(data)
+}
+
+// Merge returns a policy containing the rules of p followed by the rules of other
+func (p *Policy) Merge(other *Policy) *Policy {
+	rules := make([]Rule, 0, len(p.Rules)+len(other.Rules))
+	rules = append(rules, p.Rules...)
+	rules = append(rules, other.Rules...)
+	return &Policy{Rules: rules}
+}
+
+// Evaluate returns the violations caused by a set of changes
+func (p *Policy) Evaluate(changes []Change) []Violation {
+	violations := make([]Violation, 0)
+
+	for _, change := range changes {
+		for _, rule := range p.Rules {
+			if rule.Matches(change) {
+				violations = append(violations, Violation{Rule: rule, Change: change})
+			}
+		}
+	}
+
+	return violations
+}
+
+// Matches returns true if the change violates the rule
+func (r Rule) Matches(c Change) bool {
+	if !matchAny(r.Stacks, c.StackName) {
+		return false
+	}
+
+	if !matchAny(r.ResourceTypes, c.ResourceType) {
+		return false
+	}
+
+	if !matchAny(r.LogicalIDs, c.LogicalID) {
+		return false
+	}
+
+	if len(r.Actions) > 0 {
+		found := false
+		for _, action := range r.Actions {
+			if strings.EqualFold(action, c.Action) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if r.Replacement && c.Replacement != "True" && c.Replacement != "Conditional" {
+		return false
+	}
+
+	for key, value := range r.Tags {
+		if v, ok := c.Tags[key]; !ok || v != value {
+			return false
+		}
+	}
+
+	if r.Check != nil {
+		return r.Check(c)
+	}
+
+	return true
+}
+
+func matchAny(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, value); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ViolationError is returned when a change set breaks a policy
+type ViolationError struct {
+	Violations []Violation
+}
+
+func (e *ViolationError) Error() string {
+	lines := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		lines[i] = v.String()
+	}
+
+	return fmt.Sprintf("changeset breaks the deployment policy:\n  - %s", strings.Join(lines, "\n  - "))
+}
+
+// Overridable returns true if every violation may be overridden
+func (e *ViolationError) Overridable() bool {
+	for _, v := range e.Violations {
+		if !v.Rule.AllowOverride {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,94 @@
+package policy
+
+import (
+	"testing"
+)
+
+const testPolicy = `
+rules:
+  - name: no-rds-replacement
+    description: databases must never be replaced
+    resourceTypes: ["AWS::RDS::DBInstance"]
+    replacement: true
+  - name: protected-resources
+    actions: [Remove]
+    tags:
+      protected: "true"
+    allowOverride: true
+  - name: prod-tables
+    stacks: ["prod-*"]
+    resourceTypes: ["AWS::DynamoDB::Table"]
+    actions: [Remove]
+`
+
+func TestEvaluate(t *testing.T) {
+	p, err := Parse([]byte(testPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p = p.Merge(New(Rule{
+		Name: "no-queue-changes",
+		Check: func(c Change) bool {
+			return c.ResourceType == "AWS::SQS::Queue" && c.LogicalID == "Important"
+		},
+	}))
+
+	for _, tc := range []struct {
+		change   Change
+		expected []string
+	}{
+		{
+			change:   Change{StackName: "dev", Action: "Modify", ResourceType: "AWS::RDS::DBInstance", Replacement: "Conditional"},
+			expected: []string{"no-rds-replacement"},
+		},
+		{
+			change:   Change{StackName: "dev", Action: "Modify", ResourceType: "AWS::RDS::DBInstance", Replacement: "False"},
+			expected: []string{},
+		},
+		{
+			change:   Change{StackName: "dev", Action: "Remove", ResourceType: "AWS::S3::Bucket", Tags: map[string]string{"protected": "true"}},
+			expected: []string{"protected-resources"},
+		},
+		{
+			change:   Change{StackName: "dev", Action: "Remove", ResourceType: "AWS::S3::Bucket", Tags: map[string]string{"protected": "false"}},
+			expected: []string{},
+		},
+		{
+			change:   Change{StackName: "prod-app", Action: "Remove", ResourceType: "AWS::DynamoDB::Table"},
+			expected: []string{"prod-tables"},
+		},
+		{
+			change:   Change{StackName: "dev-app", Action: "Remove", ResourceType: "AWS::DynamoDB::Table"},
+			expected: []string{},
+		},
+		{
+			change:   Change{StackName: "dev", Action: "Add", ResourceType: "AWS::SQS::Queue", LogicalID: "Important"},
+			expected: []string{"no-queue-changes"},
+		},
+	} {
+		violations := p.Evaluate([]Change{tc.change})
+
+		actual := make([]string, len(violations))
+		for i, v := range violations {
+			actual[i] = v.Rule.Name
+		}
+
+		if len(actual) != len(tc.expected) {
+			t.Errorf("%+v: got %v, want %v", tc.change, actual, tc.expected)
+			continue
+		}
+		for i := range actual {
+			if actual[i] != tc.expected[i] {
+				t.Errorf("%+v: got %v, want %v", tc.change, actual, tc.expected)
+			}
+		}
+	}
+}
+
+func TestParseRequiresName(t *testing.T) {
+	_, err := Parse([]byte("rules:\n  - actions: [Remove]\n"))
+	if err == nil {
+		t.Error("expected an error for a rule without a name")
+	}
+}
@@ -0,0 +1,102 @@
+package status
+
+// Outcome is the result of waiting for a stack operation
+type Outcome int
+
+const (
+	// OutcomeUnsettled means the operation is still running
+	OutcomeUnsettled Outcome = iota
+	// OutcomeSuccess means the operation made the requested change.
+	// Old resources may still be being cleaned up unless SettleRules.WaitForCleanup is set.
+	OutcomeSuccess
+	// OutcomeFailure means the operation finished without making the requested change,
+	// including when the stack was rolled back successfully
+	OutcomeFailure
+	// OutcomeReview means no operation is running because the stack is waiting
+	// for a change set to be executed
+	OutcomeReview
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case OutcomeSuccess:
+		return "success"
+	case OutcomeFailure:
+		return "failure"
+	case OutcomeReview:
+		return "review"
+	default:
+		return "unsettled"
+	}
+}
+
+// IsSettled returns true if no operation is running on the stack
+func (o Outcome) IsSettled() bool {
+	return o != OutcomeUnsettled
+}
+
+// SettleRules decide when a stack operation has finished and whether it succeeded
+type SettleRules struct {
+	// Operation is the operation being waited for. If it is OperationUnknown, any operation
+	// is waited for and a stack in REVIEW_IN_PROGRESS is settled.
+	//
+	// When waiting for a create or import, REVIEW_IN_PROGRESS means the change set
+	// hasn't started executing yet. A stack which is deleted or rolled back is a failure.
+	// When waiting for a rollback, a stack which is rolled back is a success.
+	Operation Operation
+	// WaitForCleanup waits for old resources to be removed after an update or update rollback.
+	// Otherwise the outcome is known as soon as the stack enters the cleanup phase.
+	WaitForCleanup bool
+}
+
+// Outcome returns the outcome of the operation, given the stack's current status
+func (r SettleRules) Outcome(s Stack) Outcome {
+	switch {
+	case s == "REVIEW_IN_PROGRESS":
+		if r.Operation == OperationUnknown || r.Operation == OperationReview {
+			return OutcomeReview
+		}
+		return OutcomeUnsettled
+
+	case s.IsCleanup():
+		if r.WaitForCleanup {
+			return OutcomeUnsettled
+		}
+		return r.terminalOutcome(s)
+
+	case s.IsInProgress():
+		return OutcomeUnsettled
+
+	case s.IsTerminal():
+		return r.terminalOutcome(s)
+
+	default:
+		return OutcomeUnsettled
+	}
+}
+
+// terminalOutcome decides whether a stack which is no longer changing succeeded.
+// Stacks which are cleaning up are treated as having finished the operation.
+func (r SettleRules) terminalOutcome(s Stack) Outcome {
+	op := s.Operation()
+
+	switch r.Operation {
+	case OperationRollback:
+		if op == OperationRollback && !s.IsFailureStatus() {
+			return OutcomeSuccess
+		}
+		return OutcomeFailure
+
+	case OperationCreate, OperationImport:
+		// The stack may have been deleted because it failed to be created
+		if op != r.Operation {
+			return OutcomeFailure
+		}
+	}
+
+	if op == OperationRollback || s.IsFailureStatus() {
+		return OutcomeFailure
+	}
+
+	return OutcomeSuccess
+}
@@ -0,0 +1,38 @@
+package status
+
+import "testing"
+
+func TestSettleRules(t *testing.T) {
+	for _, tc := range []struct {
+		rules    SettleRules
+		status   Stack
+		expected Outcome
+	}{
+		{SettleRules{}, "REVIEW_IN_PROGRESS", OutcomeReview},
+		{SettleRules{}, "UPDATE_IN_PROGRESS", OutcomeUnsettled},
+		{SettleRules{}, "UPDATE_COMPLETE", OutcomeSuccess},
+		{SettleRules{}, "ROLLBACK_COMPLETE", OutcomeFailure},
+		{SettleRules{}, "DELETE_COMPLETE", OutcomeSuccess},
+
+		{SettleRules{Operation: OperationCreate}, "REVIEW_IN_PROGRESS", OutcomeUnsettled},
+		{SettleRules{Operation: OperationCreate}, "CREATE_COMPLETE", OutcomeSuccess},
+		{SettleRules{Operation: OperationCreate}, "ROLLBACK_COMPLETE", OutcomeFailure},
+		{SettleRules{Operation: OperationCreate}, "DELETE_COMPLETE", OutcomeFailure},
+
+		{SettleRules{Operation: OperationUpdate}, "UPDATE_COMPLETE_CLEANUP_IN_PROGRESS", OutcomeSuccess},
+		{SettleRules{Operation: OperationUpdate, WaitForCleanup: true}, "UPDATE_COMPLETE_CLEANUP_IN_PROGRESS", OutcomeUnsettled},
+		{SettleRules{Operation: OperationUpdate}, "UPDATE_ROLLBACK_COMPLETE_CLEANUP_IN_PROGRESS", OutcomeFailure},
+		{SettleRules{Operation: OperationUpdate}, "UPDATE_ROLLBACK_COMPLETE", OutcomeFailure},
+		{SettleRules{Operation: OperationUpdate}, "UPDATE_FAILED", OutcomeFailure},
+
+		{SettleRules{Operation: OperationDelete}, "DELETE_COMPLETE", OutcomeSuccess},
+		{SettleRules{Operation: OperationDelete}, "DELETE_FAILED", OutcomeFailure},
+
+		{SettleRules{Operation: OperationRollback}, "UPDATE_ROLLBACK_COMPLETE", OutcomeSuccess},
+		{SettleRules{Operation: OperationRollback}, "ROLLBACK_FAILED", OutcomeFailure},
+	} {
+		if actual := tc.rules.Outcome(tc.status); actual != tc.expected {
+			t.Errorf("%+v %s: expected %s, got %s", tc.rules, tc.status, tc.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+// Package status models CloudFormation stack and resource statuses.
+//
+// The meaning of a status depends on the operation that produced it.
+// DELETE_COMPLETE is a success when a stack is deleted, but a resource reaching
+// DELETE_COMPLETE while a stack rolls back is part of undoing a failed change.
+package status
+
+import "strings"
+
+// Operation is the kind of operation a stack is going through, or last went through
+type Operation int
+
+const (
+	// OperationUnknown is used for statuses which aren't recognised
+	OperationUnknown Operation = iota
+	// OperationReview is a stack which has been created to review a change set
+	OperationReview
+	OperationCreate
+	OperationUpdate
+	OperationDelete
+	OperationImport
+	// OperationRollback covers rolling back a create, update or import
+	OperationRollback
+)
+
+func (o Operation) String() string {
+	switch o {
+	case OperationReview:
+		return "review"
+	case OperationCreate:
+		return "create"
+	case OperationUpdate:
+		return "update"
+	case OperationDelete:
+		return "delete"
+	case OperationImport:
+		return "import"
+	case OperationRollback:
+		return "rollback"
+	default:
+		return "unknown"
+	}
+}
+
+// Category is how a status is displayed
+type Category int
+
+const (
+	Failed Category = iota
+	Complete
+	InProgress
+	Pending
+)
+
+// Stack is the status of a CloudFormation stack, such as UPDATE_COMPLETE
+type Stack string
+
+// Operation returns the operation the stack is going through.
+// Cleanup after an update or update rollback is considered part of that operation.
+func (s Stack) Operation() Operation {
+	status := string(s)
+
+	switch {
+	case status == "REVIEW_IN_PROGRESS":
+		return OperationReview
+	case strings.Contains(status, "ROLLBACK"):
+		return OperationRollback
+	case strings.HasPrefix(status, "CREATE_"):
+		return OperationCreate
+	case strings.HasPrefix(status, "UPDATE_"):
+		return OperationUpdate
+	case strings.HasPrefix(status, "DELETE_"):
+		return OperationDelete
+	case strings.HasPrefix(status, "IMPORT_"):
+		return OperationImport
+	default:
+		return OperationUnknown
+	}
+}
+
+// IsInProgress returns true if the stack is being changed, including cleaning up after a change
+// and waiting for a change set to be reviewed
+func (s Stack) IsInProgress() bool {
+	return strings.HasSuffix(string(s), "_IN_PROGRESS")
+}
+
+// IsCleanup returns true if the stack is removing old resources after an update or update rollback
+func (s Stack) IsCleanup() bool {
+	return strings.HasSuffix(string(s), "_COMPLETE_CLEANUP_IN_PROGRESS")
+}
+
+// IsTerminal returns true if the stack's last operation has finished,
+// successfully or not
+func (s Stack) IsTerminal() bool {
+	status := string(s)
+	return strings.HasSuffix(status, "_COMPLETE") || strings.HasSuffix(status, "_FAILED")
+}
+
+// IsFailureStatus returns true if the stack's last operation failed, including failing to roll back
+func (s Stack) IsFailureStatus() bool {
+	return strings.HasSuffix(string(s), "_FAILED")
+}
+
+// IsSuccess returns true if the stack's last operation finished and made the requested change
+func (s Stack) IsSuccess() bool {
+	switch s {
+	case "CREATE_COMPLETE", "UPDATE_COMPLETE", "DELETE_COMPLETE", "IMPORT_COMPLETE":
+		return true
+	}
+	return false
+}
+
+// IsFailure returns true if the stack's last operation finished without making the requested change.
+// This includes stacks which were rolled back successfully.
+func (s Stack) IsFailure() bool {
+	return s.IsTerminal() && !s.IsSuccess()
+}
+
+// IsRollback returns true if the stack is rolling back or has been rolled back
+func (s Stack) IsRollback() bool {
+	return s.Operation() == OperationRollback
+}
+
+// IsUpdatable returns true if an update can be started on the stack.
+// Stacks which failed to be created must be deleted instead, and stacks which
+// failed to roll back must have the rollback continued first.
+func (s Stack) IsUpdatable() bool {
+	switch s {
+	case "CREATE_COMPLETE", "UPDATE_COMPLETE", "UPDATE_ROLLBACK_COMPLETE", "IMPORT_COMPLETE", "IMPORT_ROLLBACK_COMPLETE":
+		return true
+	}
+	return false
+}
+
+// Category returns how the status should be displayed.
+// Rollbacks are shown as failures, even if they complete successfully,
+// and any other status which ends in _COMPLETE is shown as complete.
+func (s Stack) Category() Category {
+	switch {
+	case s == "REVIEW_IN_PROGRESS":
+		return Pending
+	case s.IsFailureStatus(), s.IsRollback():
+		return Failed
+	case s.IsInProgress():
+		return InProgress
+	case s.IsTerminal():
+		return Complete
+	default:
+		return Pending
+	}
+}
+
+// Resource is the status of a resource in a stack, such as CREATE_IN_PROGRESS
+type Resource string
+
+// IsInProgress returns true if the resource is being changed
+func (r Resource) IsInProgress() bool {
+	return strings.HasSuffix(string(r), "_IN_PROGRESS")
+}
+
+// IsFailure returns true if the resource failed to change
+func (r Resource) IsFailure() bool {
+	return strings.HasSuffix(string(r), "_FAILED")
+}
+
+// IsRollback returns true if the resource is being returned to its previous state
+func (r Resource) IsRollback() bool {
+	return strings.Contains(string(r), "ROLLBACK")
+}
+
+// Category returns how the resource status should be displayed while the stack goes through op.
+// Resources which are deleted as part of an update or delete are shown normally,
+// but resources which are deleted or rolled back while the stack rolls back are shown as failures.
+func (r Resource) Category(op Operation) Category {
+	switch {
+	case r.IsFailure():
+		return Failed
+	case r.IsRollback(), op == OperationRollback && strings.HasPrefix(string(r), "DELETE_"):
+		return Failed
+	case r.IsInProgress() && r != "REVIEW_IN_PROGRESS":
+		return InProgress
+	case strings.HasSuffix(string(r), "_COMPLETE"), r == "DELETE_SKIPPED":
+		return Complete
+	default:
+		return Pending
+	}
+}
+
+// Progress is how far a resource has got through the stack's current operation
+type Progress int
+
+const (
+	// NotStarted resources haven't been changed by the operation yet, or won't be changed at all
+	NotStarted Progress = iota
+	Started
+	Finished
+)
+
+// Progress returns how far the resource has got through op
+func (r Resource) Progress(op Operation) Progress {
+	var started, finished []Resource
+
+	switch op {
+	case OperationCreate, OperationUpdate, OperationImport, OperationReview:
+		started = []Resource{"CREATE_IN_PROGRESS", "UPDATE_IN_PROGRESS", "IMPORT_IN_PROGRESS"}
+		finished = []Resource{"CREATE_COMPLETE", "CREATE_FAILED", "UPDATE_COMPLETE", "UPDATE_FAILED", "IMPORT_COMPLETE", "IMPORT_FAILED"}
+	case OperationDelete:
+		started = []Resource{"DELETE_IN_PROGRESS"}
+		finished = []Resource{"DELETE_COMPLETE", "DELETE_FAILED", "DELETE_SKIPPED"}
+	case OperationRollback:
+		started = []Resource{"DELETE_IN_PROGRESS", "IMPORT_ROLLBACK_IN_PROGRESS", "ROLLBACK_IN_PROGRESS", "UPDATE_ROLLBACK_IN_PROGRESS"}
+		finished = []Resource{"DELETE_COMPLETE", "DELETE_FAILED", "DELETE_SKIPPED", "IMPORT_ROLLBACK_COMPLETE", "IMPORT_ROLLBACK_FAILED", "ROLLBACK_COMPLETE", "ROLLBACK_FAILED", "UPDATE_ROLLBACK_COMPLETE", "UPDATE_ROLLBACK_FAILED"}
+	}
+
+	for _, s := range started {
+		if r == s {
+			return Started
+		}
+	}
+	for _, s := range finished {
+		if r == s {
+			return Finished
+		}
+	}
+
+	return NotStarted
+}
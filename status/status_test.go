@@ -0,0 +1,76 @@
+package status
+
+import "testing"
+
+func TestStack(t *testing.T) {
+	for _, tc := range []struct {
+		status    Stack
+		op        Operation
+		terminal  bool
+		success   bool
+		rollback  bool
+		updatable bool
+		category  Category
+	}{
+		{"CREATE_IN_PROGRESS", OperationCreate, false, false, false, false, InProgress},
+		{"CREATE_COMPLETE", OperationCreate, true, true, false, true, Complete},
+		{"CREATE_FAILED", OperationCreate, true, false, false, false, Failed},
+		{"ROLLBACK_COMPLETE", OperationRollback, true, false, true, false, Failed},
+		{"UPDATE_COMPLETE_CLEANUP_IN_PROGRESS", OperationUpdate, false, false, false, false, InProgress},
+		{"UPDATE_ROLLBACK_COMPLETE", OperationRollback, true, false, true, true, Failed},
+		{"UPDATE_ROLLBACK_FAILED", OperationRollback, true, false, true, false, Failed},
+		{"DELETE_IN_PROGRESS", OperationDelete, false, false, false, false, InProgress},
+		{"DELETE_COMPLETE", OperationDelete, true, true, false, false, Complete},
+		{"DELETE_FAILED", OperationDelete, true, false, false, false, Failed},
+		{"IMPORT_ROLLBACK_COMPLETE", OperationRollback, true, false, true, true, Failed},
+		{"REVIEW_IN_PROGRESS", OperationReview, false, false, false, false, Pending},
+		{"SOMETHING_COMPLETE", OperationUnknown, true, false, false, false, Complete},
+	} {
+		if op := tc.status.Operation(); op != tc.op {
+			t.Errorf("%s: expected operation %s, got %s", tc.status, tc.op, op)
+		}
+		if tc.status.IsTerminal() != tc.terminal {
+			t.Errorf("%s: expected IsTerminal to be %v", tc.status, tc.terminal)
+		}
+		if tc.status.IsSuccess() != tc.success {
+			t.Errorf("%s: expected IsSuccess to be %v", tc.status, tc.success)
+		}
+		if tc.status.IsRollback() != tc.rollback {
+			t.Errorf("%s: expected IsRollback to be %v", tc.status, tc.rollback)
+		}
+		if tc.status.IsUpdatable() != tc.updatable {
+			t.Errorf("%s: expected IsUpdatable to be %v", tc.status, tc.updatable)
+		}
+		if c := tc.status.Category(); c != tc.category {
+			t.Errorf("%s: expected category %d, got %d", tc.status, tc.category, c)
+		}
+	}
+}
+
+func TestResource(t *testing.T) {
+	for _, tc := range []struct {
+		status   Resource
+		op       Operation
+		category Category
+		progress Progress
+	}{
+		{"CREATE_IN_PROGRESS", OperationCreate, InProgress, Started},
+		{"CREATE_COMPLETE", OperationCreate, Complete, Finished},
+		{"CREATE_FAILED", OperationCreate, Failed, Finished},
+		{"REVIEW_IN_PROGRESS", OperationCreate, Pending, NotStarted},
+		{"DELETE_COMPLETE", OperationUpdate, Complete, NotStarted},
+		{"DELETE_IN_PROGRESS", OperationDelete, InProgress, Started},
+		{"DELETE_COMPLETE", OperationDelete, Complete, Finished},
+		{"DELETE_SKIPPED", OperationDelete, Complete, Finished},
+		{"DELETE_COMPLETE", OperationRollback, Failed, Finished},
+		{"UPDATE_ROLLBACK_IN_PROGRESS", OperationRollback, Failed, Started},
+		{"CREATE_COMPLETE", OperationRollback, Complete, NotStarted},
+	} {
+		if c := tc.status.Category(tc.op); c != tc.category {
+			t.Errorf("%s during %s: expected category %d, got %d", tc.status, tc.op, tc.category, c)
+		}
+		if p := tc.status.Progress(tc.op); p != tc.progress {
+			t.Errorf("%s during %s: expected progress %d, got %d", tc.status, tc.op, tc.progress, p)
+		}
+	}
+}
@@ -5,23 +5,21 @@ import (
 	"strings"
 
 	"github.com/aws-cloudformation/rain/cft/diff"
-	"github.com/common-fate/cloudform/console"
+	"github.com/common-fate/cloudform/status"
 )
 
-type statusCategory int
+type statusCategory = status.Category
 
 const (
-	failed statusCategory = iota
-	complete
-	inProgress
-	pending
+	failed     = status.Failed
+	complete   = status.Complete
+	inProgress = status.InProgress
+	pending    = status.Pending
 )
 
-var statusColour = map[statusCategory]func(...interface{}) string{
-	pending:    console.Plain,
-	inProgress: console.Blue,
-	failed:     console.Red,
-	complete:   console.Green,
+// statusColour returns the active theme's colour function for a category of status
+func statusColour(c statusCategory) func(...interface{}) string {
+	return currentTheme().status[c]
 }
 
 type statusRep struct {
@@ -30,57 +28,32 @@ type statusRep struct {
 }
 
 func (s *statusRep) String() string {
-	return fmt.Sprint(statusColour[s.category](s.symbol))
+	return fmt.Sprint(statusColour(s.category)(s.symbol))
 }
 
-func mapStatus(status string) *statusRep {
-	rep := statusRep{}
-
-	// Colour
-	switch {
-	case status == "REVIEW_IN_PROGRESS":
-		rep.category = pending
-
-	case strings.HasSuffix(status, "_FAILED"), strings.HasPrefix(status, "DELETE_"), strings.Contains(status, "ROLLBACK"):
-		rep.category = failed
-
-	case strings.HasSuffix(status, "_IN_PROGRESS"):
-		rep.category = inProgress
-
-	case strings.HasSuffix(status, "_COMPLETE"):
-		rep.category = complete
-
-	default:
-		rep.category = pending
-	}
-
-	// Symbol
-	switch {
-	case status == "REVIEW_IN_PROGRESS":
-		rep.symbol = "."
-
-	case strings.HasSuffix(status, "_FAILED"):
-		rep.symbol = "x"
-
-	case strings.HasSuffix(status, "_IN_PROGRESS"):
-		rep.symbol = "o"
+// mapStatus returns how a stack status is displayed
+func mapStatus(stackStatus string) *statusRep {
+	return newStatusRep(status.Stack(stackStatus).Category())
+}
 
-	case strings.HasSuffix(status, "_COMPLETE"):
-		rep.symbol = "✓"
+// mapResourceStatus returns how a resource status is displayed while its stack goes through op
+func mapResourceStatus(resourceStatus string, op status.Operation) *statusRep {
+	return newStatusRep(status.Resource(resourceStatus).Category(op))
+}
 
-	default:
-		rep.symbol = "."
+func newStatusRep(c statusCategory) *statusRep {
+	return &statusRep{
+		category: c,
+		symbol:   currentTheme().symbols[c],
 	}
-
-	return &rep
 }
 
 // Colourise wraps a message in an appropriate colour
 // based on the accompanying status string
-func Colourise(msg, status string) string {
-	rep := mapStatus(status)
+func Colourise(msg, stackStatus string) string {
+	rep := mapStatus(stackStatus)
 
-	return statusColour[rep.category](msg)
+	return statusColour(rep.category)(msg)
 }
 
 // ColouriseStatus wraps a status code in an appropriate colour
@@ -88,24 +61,33 @@ func ColouriseStatus(status string) string {
 	return Colourise(status, status)
 }
 
-// ColouriseDiff wraps a diff object in nice colours
+// colouriseResourceStatus wraps a resource status in an appropriate colour
+// for the operation its stack is going through
+func colouriseResourceStatus(resourceStatus string, op status.Operation) string {
+	rep := mapResourceStatus(resourceStatus, op)
+
+	return statusColour(rep.category)(resourceStatus)
+}
+
+// ColouriseDiff wraps a diff object in the active theme's colours
 func ColouriseDiff(d diff.Diff, longFormat bool) string {
 	output := strings.Builder{}
+	theme := currentTheme()
 
 	parts := strings.Split(d.Format(longFormat), "\n")
 
 	for i, line := range parts {
 		switch {
 		case strings.HasPrefix(line, diff.Added.String()):
-			output.WriteString(console.Green(line))
+			output.WriteString(theme.added(line))
 		case strings.HasPrefix(line, diff.Removed.String()):
-			output.WriteString(console.Red(line))
+			output.WriteString(theme.removed(line))
 		case strings.HasPrefix(line, diff.Changed.String()):
-			output.WriteString(console.Blue(line))
+			output.WriteString(theme.changed(line))
 		case strings.HasPrefix(line, diff.Involved.String()):
-			output.WriteString(console.Grey(line))
+			output.WriteString(theme.involved(line))
 		default:
-			output.WriteString(console.Plain(line))
+			output.WriteString(theme.same(line))
 		}
 
 		if i < len(parts)-1 {
@@ -0,0 +1,495 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/smithy-go/ptr"
+	"github.com/common-fate/cloudform/console"
+	"github.com/common-fate/cloudform/status"
+)
+
+// ErrDetached is returned by RunDashboard when the user
+// exits the dashboard before the stack has settled
+var ErrDetached = errors.New("detached from stack before it settled")
+
+// DashboardOpts configures RunDashboard
+type DashboardOpts struct {
+	// KeepOpenOnFailure keeps the dashboard open after an operation fails
+	// until the user exits, so that the failures can be inspected
+	KeepOpenOnFailure bool
+	// PollInterval is the time between refreshes. It defaults to two seconds.
+	PollInterval time.Duration
+	// Settle decides when the stack has settled and whether its operation succeeded
+	Settle status.SettleRules
+}
+
+type dashboardPane int
+
+const (
+	paneResources dashboardPane = iota
+	paneEvents
+	paneFailures
+	paneOutputs
+	paneCount
+)
+
+var paneTitles = map[dashboardPane]string{
+	paneResources: "Resources",
+	paneEvents:    "Events",
+	paneFailures:  "Failures",
+	paneOutputs:   "Outputs",
+}
+
+// paneWeights sets the share of the screen given to each pane
+var paneWeights = map[dashboardPane]int{
+	paneResources: 4,
+	paneEvents:    3,
+	paneFailures:  2,
+	paneOutputs:   1,
+}
+
+// dashboardState is a snapshot of a stack
+type dashboardState struct {
+	stack    types.Stack
+	tree     *stackNode
+	events   []types.StackEvent
+	messages []string
+	err      error
+}
+
+type dashboard struct {
+	state      dashboardState
+	focus      dashboardPane
+	scroll     map[dashboardPane]int
+	failedOnly bool
+	finished   bool
+	started    time.Time
+}
+
+// RunDashboard shows a full screen view of a stack until it settles, with panes for the
+// resource tree, the event log, failure details and the stack outputs.
+// Use tab to move between panes, the arrow keys to scroll, f to only show failed
+// resources and q to exit. ErrDetached is returned if the user exits early.
+// If the console isn't interactive, the stack is rendered with WatchStacks instead.
+func (u *UI) RunDashboard(ctx context.Context, stackName string, opts DashboardOpts) (WatchResult, error) {
+	if !u.term.IsInteractive() {
+		results, err := u.WatchStacks(ctx, []string{stackName}, WatchOpts{PollInterval: opts.PollInterval, Settle: opts.Settle})
+		return results[0], err
+	}
+
+	interval := opts.PollInterval
+	if interval == 0 {
+		interval = time.Second * 2
+	}
+
+	restore, err := u.term.EnterFullScreen()
+	if err != nil {
+		results, err := u.WatchStacks(ctx, []string{stackName}, WatchOpts{PollInterval: opts.PollInterval, Settle: opts.Settle})
+		return results[0], err
+	}
+	defer restore()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	updates := make(chan dashboardState)
+	go u.pollDashboard(ctx, stackName, interval, updates)
+
+	// Stop reading keys before returning so that later prompts get the user's input
+	keys, stopKeys := console.ReadStdinKeys()
+	defer stopKeys()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	d := &dashboard{
+		scroll:  make(map[dashboardPane]int),
+		started: time.Now(),
+	}
+
+	collected := make(map[string]bool)
+	result := WatchResult{StackName: stackName}
+
+	finish := func() WatchResult {
+		result.Messages = make([]string, 0)
+		for message := range collected {
+			result.Messages = append(result.Messages, message)
+		}
+		return result
+	}
+
+	draw := func() {
+		width, height := u.term.Size()
+		u.term.DrawScreen(d.render(width, height, time.Now()))
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return finish(), ctx.Err()
+
+		case state := <-updates:
+			if state.err != nil {
+				return finish(), Errorf(state.err, "error watching stack '%s'", stackName)
+			}
+
+			d.state = state
+			result.StackID = ptr.ToString(state.stack.StackId)
+			result.Status = string(state.stack.StackStatus)
+			result.Outcome = opts.Settle.Outcome(status.Stack(state.stack.StackStatus))
+			for _, message := range state.messages {
+				collected[message] = true
+			}
+			if reason, ok := triggeredRollback(state.stack); ok {
+				result.RollbackTriggered = true
+				collected[reason] = true
+			}
+
+			if result.Outcome.IsSettled() && !d.finished {
+				if !opts.KeepOpenOnFailure || result.Outcome != status.OutcomeFailure {
+					return finish(), nil
+				}
+				d.finished = true
+				d.focus = paneFailures
+			}
+
+			draw()
+
+		case key, ok := <-keys:
+			if !ok {
+				keys = nil
+				continue
+			}
+
+			if d.handleKey(key) {
+				if d.finished {
+					return finish(), nil
+				}
+				return finish(), ErrDetached
+			}
+
+			draw()
+
+		case <-ticker.C:
+			draw()
+		}
+	}
+}
+
+// pollDashboard sends a snapshot of the stack on updates until ctx is cancelled
+func (u *UI) pollDashboard(ctx context.Context, stackName string, interval time.Duration, updates chan<- dashboardState) {
+	stackID := stackName
+	progress := newProgressTracker(u.durations)
+	slow := newSlowTracker(u.slow)
+
+	for {
+		var state dashboardState
+
+		state.stack, state.err = u.cfnClient.GetStack(ctx, stackID)
+		if state.err == nil {
+			// Refresh the stack ID so we can deal with deleted stacks ok
+			stackID = ptr.ToString(state.stack.StackId)
+
+			state.tree, state.messages = u.buildStackNode(ctx, state.stack, ptr.ToString(state.stack.StackName))
+			progress.observe(state.tree, "")
+			slow.check(ctx, u, state.tree, stackID, time.Now())
+
+			// We ignore errors because it just means we'll show no events
+			state.events, _ = u.cfnClient.GetStackEvents(ctx, stackID, 100)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case updates <- state:
+		}
+
+		if state.err != nil {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// handleKey updates the dashboard for a key press and returns true if the dashboard should exit
+func (d *dashboard) handleKey(key console.Key) bool {
+	switch key {
+	case "q", console.KeyCtrlC, console.KeyEscape:
+		return true
+	case console.KeyTab, console.KeyRight:
+		d.focus = (d.focus + 1) % paneCount
+	case console.KeyLeft:
+		d.focus = (d.focus + paneCount - 1) % paneCount
+	case console.KeyDown, "j":
+		d.scroll[d.focus]++
+	case console.KeyUp, "k":
+		d.scroll[d.focus]--
+	case console.KeyPageDown:
+		d.scroll[d.focus] += 10
+	case console.KeyPageUp:
+		d.scroll[d.focus] -= 10
+	case console.KeyHome, "g":
+		d.scroll[d.focus] = 0
+	case "f":
+		d.failedOnly = !d.failedOnly
+		d.scroll[paneResources] = 0
+	}
+
+	return false
+}
+
+// render returns the lines of the dashboard for a screen of the given size
+func (d *dashboard) render(width, height int, now time.Time) []string {
+	lines := make([]string, 0, height)
+
+	// Title
+	title := "Waiting for stack..."
+	if d.state.tree != nil {
+		title = fmt.Sprintf("%s: %s %s",
+			console.Yellow(fmt.Sprintf("Stack %s", d.state.tree.name)),
+			ColouriseStatus(d.state.tree.status),
+			console.Grey(now.Sub(d.started).Truncate(time.Second).String()),
+		)
+	}
+	lines = append(lines, console.Truncate(title, width))
+
+	// The countdown takes a line only while the stack is being monitored
+	if remaining, ok := monitoringRemaining(d.state.stack, d.state.tree, now); ok {
+		lines = append(lines, console.Truncate(renderMonitoring(d.state.stack, remaining), width))
+	}
+
+	// Panes share the space between the title and the help line
+	available := height - len(lines) - 1
+	if available < int(paneCount)*2 {
+		available = int(paneCount) * 2
+	}
+
+	totalWeight := 0
+	for _, w := range paneWeights {
+		totalWeight += w
+	}
+
+	used := 0
+	for pane := dashboardPane(0); pane < paneCount; pane++ {
+		size := available * paneWeights[pane] / totalWeight
+		if pane == paneCount-1 {
+			size = available - used
+		}
+		if size < 2 {
+			size = 2
+		}
+		used += size
+
+		lines = append(lines, d.renderPane(pane, width, size, now)...)
+	}
+
+	// Help
+	exit := "detach"
+	if d.finished {
+		exit = "exit"
+	}
+	help := fmt.Sprintf("[tab] next pane  [%s] scroll  [f] failed only  [q] %s", ActiveTheme().Symbols.ScrollKeys, exit)
+	lines = append(lines, console.Grey(console.Truncate(help, width)))
+
+	return lines
+}
+
+// renderPane returns exactly height lines for a pane, including its title
+func (d *dashboard) renderPane(pane dashboardPane, width, height int, now time.Time) []string {
+	content := d.paneContent(pane, width, now)
+
+	rows := height - 1
+	maxScroll := len(content) - rows
+	if maxScroll < 0 {
+		maxScroll = 0
+	}
+	if d.scroll[pane] > maxScroll {
+		d.scroll[pane] = maxScroll
+	}
+	if d.scroll[pane] < 0 {
+		d.scroll[pane] = 0
+	}
+	offset := d.scroll[pane]
+
+	title := fmt.Sprintf(" %s ", paneTitles[pane])
+	if pane == paneResources && d.failedOnly {
+		title = fmt.Sprintf(" %s (failed only) ", paneTitles[pane])
+	}
+	if len(content) > rows {
+		title += fmt.Sprintf("%d-%d of %d ", offset+1, offset+rows, len(content))
+	}
+
+	ruleSymbol := ActiveTheme().Symbols.Rule
+	rule := strings.Repeat(ruleSymbol, maxInt(width-console.StringWidth(title)-2, 0))
+	if pane == d.focus {
+		title = console.White(title)
+	} else {
+		title = console.Bold(title)
+	}
+
+	lines := []string{console.Truncate(ruleSymbol+ruleSymbol+title+rule, width)}
+
+	for i := 0; i < rows; i++ {
+		line := ""
+		if offset+i < len(content) {
+			line = console.Truncate(content[offset+i], width)
+		}
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+// paneContent returns every line of a pane, before scrolling
+func (d *dashboard) paneContent(pane dashboardPane, width int, now time.Time) []string {
+	switch pane {
+	case paneResources:
+		if d.state.tree == nil {
+			return nil
+		}
+		tree := d.state.tree
+		if d.failedOnly {
+			tree = filterFailed(tree)
+		}
+		// Skip the header as it's shown in the title
+		return treeLines(tree, 0, foldNone, width, true, now)[1:]
+
+	case paneEvents:
+		lines := make([]string, 0, len(d.state.events))
+		for _, event := range d.state.events {
+			status := string(event.ResourceStatus)
+			line := fmt.Sprintf("%s %s %s %s",
+				console.Grey(ptr.ToTime(event.Timestamp).Local().Format("15:04:05")),
+				ColouriseStatus(status),
+				ptr.ToString(event.ResourceType),
+				console.Yellow(ptr.ToString(event.LogicalResourceId)),
+			)
+			if event.ResourceStatusReason != nil {
+				line += " " + Colourise(ptr.ToString(event.ResourceStatusReason), status)
+			}
+			lines = append(lines, line)
+		}
+		return lines
+
+	case paneFailures:
+		lines := make([]string, 0)
+		for _, f := range failedResources(d.state.tree, "") {
+			header := fmt.Sprintf("%s %s %s", console.Yellow(f.path), f.resourceType, ColouriseStatus(f.status))
+			if f.physicalID != "" {
+				header += console.Grey(fmt.Sprintf(" (%s)", f.physicalID))
+			}
+			lines = append(lines, header)
+			for _, line := range wrap(f.reason, width-4) {
+				lines = append(lines, "    "+statusColour(failed)(line))
+			}
+		}
+		if len(lines) == 0 {
+			lines = append(lines, console.Grey("No failures"))
+		}
+		return lines
+
+	case paneOutputs:
+		lines := make([]string, 0, len(d.state.stack.Outputs))
+		for _, output := range d.state.stack.Outputs {
+			lines = append(lines, fmt.Sprintf("%s: %s", console.Yellow(ptr.ToString(output.OutputKey)), ptr.ToString(output.OutputValue)))
+		}
+		return lines
+	}
+
+	return nil
+}
+
+// failedResource is a failed resource with its path through any nested stacks
+type failedResource struct {
+	path string
+	*resourceNode
+}
+
+// failedResources lists the failed resources in a tree
+func failedResources(node *stackNode, prefix string) []failedResource {
+	out := make([]failedResource, 0)
+	if node == nil {
+		return out
+	}
+
+	for _, r := range node.resources {
+		if r.nested != nil {
+			out = append(out, failedResources(r.nested, prefix+r.logicalID+"/")...)
+		}
+		if mapResourceStatus(r.status, node.operation()).category == failed && r.reason != "" {
+			out = append(out, failedResource{path: prefix + r.logicalID, resourceNode: r})
+		}
+	}
+
+	return out
+}
+
+// filterFailed returns a copy of a tree which only contains failed resources
+// and the nested stacks which contain them
+func filterFailed(node *stackNode) *stackNode {
+	out := *node
+	out.resources = make([]*resourceNode, 0)
+
+	for _, r := range node.resources {
+		if r.nested != nil {
+			nested := filterFailed(r.nested)
+			if len(nested.resources) > 0 || mapStatus(nested.status).category == failed {
+				filtered := *r
+				filtered.nested = nested
+				out.resources = append(out.resources, &filtered)
+			}
+			continue
+		}
+
+		if mapResourceStatus(r.status, node.operation()).category == failed {
+			out.resources = append(out.resources, r)
+		}
+	}
+
+	return &out
+}
+
+// wrap splits text into lines of at most width columns.
+// Words which are wider than a line are left on a line of their own.
+func wrap(text string, width int) []string {
+	if width < 10 {
+		width = 10
+	}
+
+	lines := make([]string, 0)
+	line := ""
+
+	for _, word := range strings.Fields(text) {
+		if line != "" && console.StringWidth(line)+1+console.StringWidth(word) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+
+	if line != "" {
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,241 @@
+package ui
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/common-fate/cloudform/console"
+)
+
+func failedTree() *stackNode {
+	return &stackNode{
+		name:   "app",
+		status: "CREATE_FAILED",
+		resources: []*resourceNode{
+			{logicalID: "Bucket", resourceType: "AWS::S3::Bucket", status: "CREATE_COMPLETE"},
+			{logicalID: "Role", resourceType: "AWS::IAM::Role", status: "CREATE_FAILED", reason: "Access denied"},
+			{logicalID: "Queue", resourceType: "AWS::SQS::Queue", status: "CREATE_FAILED"},
+			{logicalID: "Database", resourceType: "AWS::CloudFormation::Stack", status: "CREATE_FAILED", reason: "Embedded stack failed", nested: &stackNode{
+				name:   "Database",
+				status: "CREATE_FAILED",
+				resources: []*resourceNode{
+					{logicalID: "Table", resourceType: "AWS::DynamoDB::Table", status: "CREATE_FAILED", reason: "Table already exists"},
+					{logicalID: "Key", resourceType: "AWS::KMS::Key", status: "CREATE_COMPLETE"},
+				},
+			}},
+			{logicalID: "Cache", resourceType: "AWS::CloudFormation::Stack", status: "CREATE_COMPLETE", nested: &stackNode{
+				name:   "Cache",
+				status: "CREATE_COMPLETE",
+				resources: []*resourceNode{
+					{logicalID: "Cluster", resourceType: "AWS::ElastiCache::CacheCluster", status: "CREATE_COMPLETE"},
+				},
+			}},
+		},
+	}
+}
+
+func TestDashboardHandleKey(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		focus      dashboardPane
+		scroll     int
+		key        console.Key
+		exit       bool
+		focusAfter dashboardPane
+		scrollTo   int
+		failedOnly bool
+	}{
+		{name: "quit", key: "q", exit: true},
+		{name: "ctrl-c", key: console.KeyCtrlC, exit: true},
+		{name: "escape", key: console.KeyEscape, exit: true},
+		{name: "tab", key: console.KeyTab, focusAfter: paneEvents},
+		{name: "tab wraps", focus: paneOutputs, key: console.KeyTab, focusAfter: paneResources},
+		{name: "left wraps", key: console.KeyLeft, focusAfter: paneOutputs},
+		{name: "down", focus: paneEvents, key: console.KeyDown, focusAfter: paneEvents, scrollTo: 1},
+		{name: "up", scroll: 3, key: "k", scrollTo: 2},
+		{name: "page down", scroll: 1, key: console.KeyPageDown, scrollTo: 11},
+		{name: "home", scroll: 7, key: "g", scrollTo: 0},
+		{name: "failed only", scroll: 4, key: "f", failedOnly: true},
+		{name: "unknown key", scroll: 2, key: "z", scrollTo: 2},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			d := &dashboard{focus: tc.focus, scroll: map[dashboardPane]int{tc.focus: tc.scroll}}
+
+			if exit := d.handleKey(tc.key); exit != tc.exit {
+				t.Fatalf("expected exit to be %v, got %v", tc.exit, exit)
+			}
+			if tc.exit {
+				return
+			}
+
+			if d.focus != tc.focusAfter {
+				t.Errorf("expected focus %d, got %d", tc.focusAfter, d.focus)
+			}
+			if d.scroll[tc.focus] != tc.scrollTo {
+				t.Errorf("expected scroll %d, got %d", tc.scrollTo, d.scroll[tc.focus])
+			}
+			if d.failedOnly != tc.failedOnly {
+				t.Errorf("expected failed only to be %v", tc.failedOnly)
+			}
+		})
+	}
+}
+
+func TestFilterFailed(t *testing.T) {
+	tree := failedTree()
+	filtered := filterFailed(tree)
+
+	var actual []string
+	for _, r := range filtered.resources {
+		actual = append(actual, r.logicalID)
+		if r.nested != nil {
+			for _, nested := range r.nested.resources {
+				actual = append(actual, r.logicalID+"/"+nested.logicalID)
+			}
+		}
+	}
+
+	expected := []string{"Role", "Queue", "Database", "Database/Table"}
+	if d := cmp.Diff(expected, actual); d != "" {
+		t.Error(d)
+	}
+
+	if len(tree.resources) != 5 || len(tree.resources[3].nested.resources) != 2 {
+		t.Error("expected the original tree to be unchanged")
+	}
+}
+
+func TestFailedResources(t *testing.T) {
+	var actual []string
+	for _, f := range failedResources(failedTree(), "") {
+		actual = append(actual, f.path+": "+f.reason)
+	}
+
+	// Resources without a reason are left out, as there's nothing more to show for them
+	expected := []string{
+		"Role: Access denied",
+		"Database/Table: Table already exists",
+		"Database: Embedded stack failed",
+	}
+	if d := cmp.Diff(expected, actual); d != "" {
+		t.Error(d)
+	}
+
+	if len(failedResources(nil, "")) != 0 {
+		t.Error("expected no failures without a tree")
+	}
+}
+
+func TestWrap(t *testing.T) {
+	for _, tc := range []struct {
+		text     string
+		width    int
+		expected []string
+	}{
+		{"", 20, []string{}},
+		{"short", 20, []string{"short"}},
+		{"the quick brown fox jumps", 10, []string{"the quick", "brown fox", "jumps"}},
+		{"  extra   spaces  ", 20, []string{"extra spaces"}},
+		{"averyveryverylongword fits", 10, []string{"averyveryverylongword", "fits"}},
+		// Wide characters take up two columns each
+		{"スタック 作成 失敗", 10, []string{"スタック", "作成 失敗"}},
+		// Widths below ten are raised to ten
+		{"one two three", 3, []string{"one two", "three"}},
+	} {
+		actual := wrap(tc.text, tc.width)
+		if d := cmp.Diff(tc.expected, actual); d != "" {
+			t.Errorf("wrap(%q, %d): %s", tc.text, tc.width, d)
+		}
+	}
+}
+
+func TestDashboardRender(t *testing.T) {
+	setColour(t, false)
+
+	now := time.Now()
+	d := &dashboard{
+		state:      dashboardState{tree: failedTree()},
+		focus:      paneFailures,
+		scroll:     make(map[dashboardPane]int),
+		failedOnly: true,
+		started:    now.Add(-75 * time.Second),
+	}
+
+	for _, tc := range []struct {
+		theme    string
+		expected []string
+	}{
+		{
+			theme: "default",
+			expected: []string{
+				"Stack app: CREATE_FAILED 1m15s",
+				"── Resources (failed only) 1-3 of 4 ──────",
+				"  x AWS::IAM::Role Role Access denied",
+				"  x AWS::SQS::Queue Queue",
+				"  - Stack Database: CREATE_FAILED",
+				"── Events ────────────────────────────────",
+				"",
+				"",
+				"── Failures 1-1 of 6 ─────────────────────",
+				"Role AWS::IAM::Role CREATE_FAILED",
+				"── Outputs ───────────────────────────────",
+				"",
+				"[tab] next pane  [↑/↓] scroll  [f] failed ",
+			},
+		},
+		{
+			theme: "ascii",
+			expected: []string{
+				"Stack app: CREATE_FAILED 1m15s",
+				"-- Resources (failed only) 1-3 of 4 ------",
+				"  x AWS::IAM::Role Role Access denied",
+				"  x AWS::SQS::Queue Queue",
+				"  - Stack Database: CREATE_FAILED",
+				"-- Events --------------------------------",
+				"",
+				"",
+				"-- Failures 1-1 of 6 ---------------------",
+				"Role AWS::IAM::Role CREATE_FAILED",
+				"-- Outputs -------------------------------",
+				"",
+				"[tab] next pane  [up/down] scroll  [f] fai",
+			},
+		},
+	} {
+		t.Run(tc.theme, func(t *testing.T) {
+			setTheme(t, Themes[tc.theme])
+
+			var buf bytes.Buffer
+			term := console.NewTerminal(&buf, console.WithTTY(true), console.WithSize(42, 13))
+			width, height := term.Size()
+			term.DrawScreen(d.render(width, height, now))
+
+			if d := cmp.Diff(tc.expected, screenLines(buf.String())); d != "" {
+				t.Error(d)
+			}
+		})
+	}
+}
+
+// screenLines returns the lines drawn by console.DrawScreen without its escape codes
+func screenLines(screen string) []string {
+	for _, code := range []string{"\033[H", "\033[K", "\033[J"} {
+		screen = strings.ReplaceAll(screen, code, "")
+	}
+	return strings.Split(screen, "\r\n")
+}
+
+// setTheme changes the theme for the rest of a test
+func setTheme(t *testing.T, theme Theme) {
+	previous := ActiveTheme()
+	t.Cleanup(func() { SetTheme(previous) })
+
+	err := SetTheme(theme)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
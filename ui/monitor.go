@@ -0,0 +1,128 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/smithy-go/ptr"
+	"github.com/common-fate/cloudform/console"
+	"github.com/common-fate/cloudform/status"
+)
+
+// monitoringPeriod returns how long CloudFormation watches the stack's rollback triggers
+// after its resources have been deployed, or zero if the stack has no monitoring period
+func monitoringPeriod(stack types.Stack) time.Duration {
+	if stack.RollbackConfiguration == nil {
+		return 0
+	}
+	return time.Duration(ptr.ToInt32(stack.RollbackConfiguration.MonitoringTimeInMinutes)) * time.Minute
+}
+
+// monitoringRemaining returns how long is left of the monitoring period, if the stack
+// is being monitored. A stack is monitored once every resource of a create or update
+// has settled, until the period has passed since the last resource finished.
+func monitoringRemaining(stack types.Stack, node *stackNode, now time.Time) (time.Duration, bool) {
+	period := monitoringPeriod(stack)
+	if period == 0 || node == nil {
+		return 0, false
+	}
+
+	s := status.Stack(node.status)
+	if !s.IsInProgress() || s.IsCleanup() {
+		return 0, false
+	}
+	if op := s.Operation(); op != status.OperationCreate && op != status.OperationUpdate {
+		return 0, false
+	}
+
+	settledAt, settled := lastSettled(node)
+	if !settled {
+		return 0, false
+	}
+	if settledAt.Before(node.started) {
+		settledAt = node.started
+	}
+
+	remaining := period - now.Sub(settledAt)
+	if remaining < 0 {
+		remaining = 0
+	}
+	return remaining, true
+}
+
+// lastSettled returns when the last resource in the tree finished,
+// or false if any resource is still pending or in progress
+func lastSettled(node *stackNode) (time.Time, bool) {
+	var last time.Time
+	op := node.operation()
+
+	for _, r := range node.resources {
+		switch r.category(op) {
+		case inProgress, pending:
+			return time.Time{}, false
+		}
+
+		if r.since.After(last) {
+			last = r.since
+		}
+
+		if r.nested != nil {
+			nested, ok := lastSettled(r.nested)
+			if !ok {
+				return time.Time{}, false
+			}
+			if nested.After(last) {
+				last = nested
+			}
+		}
+	}
+
+	return last, true
+}
+
+// renderMonitoring renders the monitoring phase of a stack with a countdown
+func renderMonitoring(stack types.Stack, remaining time.Duration) string {
+	triggers := 0
+	if stack.RollbackConfiguration != nil {
+		triggers = len(stack.RollbackConfiguration.RollbackTriggers)
+	}
+
+	line := fmt.Sprintf("  %s %s", statusColour(inProgress)("Monitoring rollback triggers"), console.Grey(fmt.Sprintf("(%d alarms)", triggers)))
+	if remaining < time.Second {
+		return line + console.Grey(", finishing")
+	}
+	return line + console.Grey(fmt.Sprintf(", %s left", formatRemaining(remaining)))
+}
+
+// triggeredRollback returns the stack's status reason if it is being rolled back
+// because one of its rollback triggers went into alarm
+func triggeredRollback(stack types.Stack) (string, bool) {
+	if status.Stack(stack.StackStatus).Operation() != status.OperationRollback {
+		return "", false
+	}
+	if stack.RollbackConfiguration == nil || len(stack.RollbackConfiguration.RollbackTriggers) == 0 {
+		return "", false
+	}
+
+	reason := ptr.ToString(stack.StackStatusReason)
+	if reason == "" {
+		return "", false
+	}
+
+	for _, trigger := range stack.RollbackConfiguration.RollbackTriggers {
+		arn := ptr.ToString(trigger.Arn)
+		if arn == "" {
+			continue
+		}
+
+		// Alarm ARNs end with the alarm name, which may be all the reason mentions
+		name := arn[strings.LastIndex(arn, ":")+1:]
+		if strings.Contains(reason, arn) || (name != "" && strings.Contains(reason, name)) {
+			return reason, true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,102 @@
+package ui
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/smithy-go/ptr"
+)
+
+func TestMonitoringRemaining(t *testing.T) {
+	started := time.Now().Add(-10 * time.Minute)
+	settled := started.Add(4 * time.Minute)
+	now := settled.Add(2 * time.Minute)
+
+	stack := types.Stack{
+		RollbackConfiguration: &types.RollbackConfiguration{
+			MonitoringTimeInMinutes: ptr.Int32(15),
+			RollbackTriggers:        []types.RollbackTrigger{{Arn: ptr.String("arn:aws:cloudwatch:us-east-1:123456789012:alarm:errors")}},
+		},
+	}
+
+	node := func(stackStatus, tableStatus string) *stackNode {
+		return &stackNode{
+			name:    "app",
+			status:  stackStatus,
+			started: started,
+			resources: []*resourceNode{
+				{logicalID: "Bucket", status: "UPDATE_COMPLETE", since: started.Add(time.Minute)},
+				{logicalID: "Database", resourceType: "AWS::CloudFormation::Stack", status: "UPDATE_COMPLETE", since: started.Add(2 * time.Minute), nested: &stackNode{
+					name:   "Database",
+					status: "UPDATE_COMPLETE",
+					resources: []*resourceNode{
+						{logicalID: "Table", status: tableStatus, since: settled},
+					},
+				}},
+			},
+		}
+	}
+
+	for _, tc := range []struct {
+		name      string
+		stack     types.Stack
+		node      *stackNode
+		remaining time.Duration
+		ok        bool
+	}{
+		{"monitoring", stack, node("UPDATE_IN_PROGRESS", "UPDATE_COMPLETE"), 13 * time.Minute, true},
+		{"resources still changing", stack, node("UPDATE_IN_PROGRESS", "UPDATE_IN_PROGRESS"), 0, false},
+		{"no monitoring period", types.Stack{}, node("UPDATE_IN_PROGRESS", "UPDATE_COMPLETE"), 0, false},
+		{"finished", stack, node("UPDATE_COMPLETE", "UPDATE_COMPLETE"), 0, false},
+		{"rolling back", stack, node("UPDATE_ROLLBACK_IN_PROGRESS", "UPDATE_COMPLETE"), 0, false},
+	} {
+		remaining, ok := monitoringRemaining(tc.stack, tc.node, now)
+		if ok != tc.ok || remaining != tc.remaining {
+			t.Errorf("%s: expected %s %t, got %s %t", tc.name, tc.remaining, tc.ok, remaining, ok)
+		}
+	}
+
+	// The countdown stops at zero while CloudFormation finishes up
+	remaining, ok := monitoringRemaining(stack, node("UPDATE_IN_PROGRESS", "UPDATE_COMPLETE"), settled.Add(time.Hour))
+	if !ok || remaining != 0 {
+		t.Errorf("expected no time remaining, got %s %t", remaining, ok)
+	}
+}
+
+func TestTriggeredRollback(t *testing.T) {
+	config := &types.RollbackConfiguration{
+		RollbackTriggers: []types.RollbackTrigger{{Arn: ptr.String("arn:aws:cloudwatch:us-east-1:123456789012:alarm:api-errors")}},
+	}
+
+	for _, tc := range []struct {
+		name   string
+		stack  types.Stack
+		expect bool
+	}{
+		{"alarm", types.Stack{
+			StackStatus:           "UPDATE_ROLLBACK_IN_PROGRESS",
+			StackStatusReason:     ptr.String("Rollback triggered by alarm(s): api-errors"),
+			RollbackConfiguration: config,
+		}, true},
+		{"resource failure", types.Stack{
+			StackStatus:           "UPDATE_ROLLBACK_IN_PROGRESS",
+			StackStatusReason:     ptr.String("The following resource(s) failed to update: [Function]."),
+			RollbackConfiguration: config,
+		}, false},
+		{"not rolling back", types.Stack{
+			StackStatus:           "UPDATE_IN_PROGRESS",
+			StackStatusReason:     ptr.String("api-errors"),
+			RollbackConfiguration: config,
+		}, false},
+		{"no triggers", types.Stack{
+			StackStatus:       "UPDATE_ROLLBACK_IN_PROGRESS",
+			StackStatusReason: ptr.String("Rollback triggered by alarm(s): api-errors"),
+		}, false},
+	} {
+		_, ok := triggeredRollback(tc.stack)
+		if ok != tc.expect {
+			t.Errorf("%s: expected %t, got %t", tc.name, tc.expect, ok)
+		}
+	}
+}
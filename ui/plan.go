@@ -0,0 +1,210 @@
+package ui
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/smithy-go/ptr"
+	"github.com/common-fate/cloudform/console"
+)
+
+// PlanFileVersion is the version of the plan file format written by WritePlanFile
+const PlanFileVersion = 1
+
+// PlanFile is a saved description of a change set which
+// can be rendered without access to AWS
+type PlanFile struct {
+	Version   int        `json:"version"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ChangeSet *ChangeSet `json:"changeSet"`
+}
+
+// ChangeSet describes a change set along with any nested change sets
+type ChangeSet struct {
+	StackName     string           `json:"stackName"`
+	StackID       string           `json:"stackId,omitempty"`
+	ChangeSetName string           `json:"changeSetName"`
+	ChangeSetID   string           `json:"changeSetId,omitempty"`
+	Status        string           `json:"status,omitempty"`
+	Changes       []ResourceChange `json:"changes"`
+}
+
+// ResourceChange describes the change to a single resource
+type ResourceChange struct {
+	Action             string   `json:"action"`
+	LogicalResourceID  string   `json:"logicalResourceId"`
+	PhysicalResourceID string   `json:"physicalResourceId,omitempty"`
+	ResourceType       string   `json:"resourceType"`
+	Replacement        string   `json:"replacement,omitempty"`
+	Scope              []string `json:"scope,omitempty"`
+	// Nested is the change set of a nested stack
+	Nested *ChangeSet `json:"nested,omitempty"`
+}
+
+// DescribeChangeSet fetches the named change set, and any nested change sets, from CloudFormation
+func (u *UI) DescribeChangeSet(ctx context.Context, stackName, changeSetName string) (*ChangeSet, error) {
+	status, err := u.cfnClient.GetChangeSet(ctx, stackName, changeSetName)
+	if err != nil {
+		return nil, Errorf(err, "error getting changeset '%s' for stack '%s'", changeSetName, stackName)
+	}
+
+	cs := ChangeSet{
+		StackName:     ptr.ToString(status.StackName),
+		StackID:       ptr.ToString(status.StackId),
+		ChangeSetName: ptr.ToString(status.ChangeSetName),
+		ChangeSetID:   ptr.ToString(status.ChangeSetId),
+		Status:        string(status.Status),
+		Changes:       make([]ResourceChange, 0),
+	}
+
+	for _, change := range status.Changes {
+		if change.ResourceChange == nil {
+			continue
+		}
+
+		rc := ResourceChange{
+			Action:             string(change.ResourceChange.Action),
+			LogicalResourceID:  ptr.ToString(change.ResourceChange.LogicalResourceId),
+			PhysicalResourceID: ptr.ToString(change.ResourceChange.PhysicalResourceId),
+			ResourceType:       ptr.ToString(change.ResourceChange.ResourceType),
+			Replacement:        string(change.ResourceChange.Replacement),
+		}
+
+		for _, scope := range change.ResourceChange.Scope {
+			rc.Scope = append(rc.Scope, string(scope))
+		}
+
+		if change.ResourceChange.ChangeSetId != nil {
+			rc.Nested, err = u.DescribeChangeSet(ctx, "", ptr.ToString(change.ResourceChange.ChangeSetId))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		cs.Changes = append(cs.Changes, rc)
+	}
+
+	return &cs, nil
+}
+
+// RenderChangeSet returns a pretty representation of a change set
+func RenderChangeSet(cs *ChangeSet) string {
+	out := strings.Builder{}
+
+	out.WriteString(fmt.Sprintf("%s:\n", console.Yellow(fmt.Sprintf("Stack %s", cs.StackName))))
+
+	// Non-stack resources
+	for _, change := range cs.Changes {
+		if change.Nested != nil {
+			// Bunch up nested stacks to the end
+			continue
+		}
+
+		line := fmt.Sprintf("%s %s", change.ResourceType, change.LogicalResourceID)
+
+		out.WriteString(formatAction(change.Action, line))
+		out.WriteString("\n")
+	}
+
+	// Nested stacks
+	for _, change := range cs.Changes {
+		if change.Nested == nil {
+			continue
+		}
+
+		parts := strings.SplitN(RenderChangeSet(change.Nested), "\n", 2)
+
+		out.WriteString(formatAction(change.Action, parts[0]))
+		out.WriteString("\n")
+
+		if len(parts) > 1 {
+			for _, line := range strings.Split(parts[1], "\n") {
+				out.WriteString("  " + line + "\n")
+			}
+		}
+	}
+
+	return strings.TrimSpace(out.String())
+}
+
+func formatAction(action string, line string) string {
+	switch types.ChangeAction(action) {
+	case types.ChangeAction("Add"):
+		return currentTheme().added("  + " + line)
+	case types.ChangeAction("Modify"):
+		return currentTheme().changed("  > " + line)
+	case types.ChangeAction("Remove"):
+		return currentTheme().removed("  - " + line)
+	}
+
+	return ""
+}
+
+// NewPlanFile creates a plan file for the given change set
+func NewPlanFile(cs *ChangeSet) *PlanFile {
+	return &PlanFile{
+		Version:   PlanFileVersion,
+		CreatedAt: time.Now().UTC(),
+		ChangeSet: cs,
+	}
+}
+
+// WritePlanFile writes a plan file as JSON
+func WritePlanFile(w io.Writer, pf *PlanFile) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(pf)
+}
+
+// ReadPlanFile reads a plan file written by WritePlanFile
+func ReadPlanFile(r io.Reader) (*PlanFile, error) {
+	var pf PlanFile
+
+	err := json.NewDecoder(r).Decode(&pf)
+	if err != nil {
+		return nil, fmt.Errorf("reading plan file: %w", err)
+	}
+
+	if pf.Version != PlanFileVersion {
+		return nil, fmt.Errorf("unsupported plan file version %d (expected %d)", pf.Version, PlanFileVersion)
+	}
+
+	if pf.ChangeSet == nil {
+		return nil, fmt.Errorf("plan file does not contain a changeset")
+	}
+
+	return &pf, nil
+}
+
+// SavePlanFile writes a plan file to the given path
+func SavePlanFile(path string, pf *PlanFile) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return WritePlanFile(f, pf)
+}
+
+// LoadPlanFile reads a plan file from the given path
+func LoadPlanFile(path string) (*PlanFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadPlanFile(f)
+}
+
+// RenderPlanFile returns a pretty representation of a saved plan
+func RenderPlanFile(pf *PlanFile) string {
+	return RenderChangeSet(pf.ChangeSet)
+}
@@ -0,0 +1,57 @@
+package ui
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRenderPlanFile(t *testing.T) {
+	setColour(t, false)
+
+	pf, err := LoadPlanFile("testdata/plan.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := os.ReadFile("testdata/plan.golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d := cmp.Diff(strings.TrimSpace(string(expected)), RenderPlanFile(pf)); d != "" {
+		t.Error(d)
+	}
+}
+
+func TestPlanFileRoundTrip(t *testing.T) {
+	pf, err := LoadPlanFile("testdata/plan.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	err = WritePlanFile(&buf, pf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := ReadPlanFile(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d := cmp.Diff(pf, actual); d != "" {
+		t.Error(d)
+	}
+}
+
+func TestReadPlanFileVersion(t *testing.T) {
+	_, err := ReadPlanFile(strings.NewReader(`{"version": 99, "changeSet": {}}`))
+	if err == nil {
+		t.Error("expected an error for an unsupported version")
+	}
+}
@@ -0,0 +1,205 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/common-fate/cloudform/console"
+	"github.com/common-fate/cloudform/estimate"
+	"github.com/common-fate/cloudform/status"
+)
+
+// progressBarWidth is the widest the progress bar is drawn
+const progressBarWidth = 30
+
+// operationStart returns when the stack's current operation started
+func operationStart(stack types.Stack) time.Time {
+	var started time.Time
+	for _, t := range []*time.Time{stack.CreationTime, stack.LastUpdatedTime, stack.DeletionTime} {
+		if t != nil && t.After(started) {
+			started = *t
+		}
+	}
+	return started
+}
+
+// changeAction converts a change set action into the action used to record durations
+func changeAction(action types.ChangeAction) string {
+	switch action {
+	case types.ChangeActionAdd:
+		return "create"
+	case types.ChangeActionRemove:
+		return "delete"
+	case types.ChangeActionImport:
+		return "import"
+	default:
+		return "update"
+	}
+}
+
+// statusAction returns the action a resource status belongs to, such as "create" for CREATE_COMPLETE.
+// Rollbacks return "rollback".
+func statusAction(s string) string {
+	if status.Resource(s).IsRollback() {
+		return "rollback"
+	}
+	action, _, _ := strings.Cut(s, "_")
+	return strings.ToLower(action)
+}
+
+// progressTracker follows a stack's resources through an operation,
+// recording how long each resource took and estimating how long is left
+type progressTracker struct {
+	store estimate.Store
+	// deps are the dependencies between the stack's resources, read from its template
+	deps       map[string][]string
+	depsLoaded bool
+	// starts is when each resource was seen to start changing, keyed by its path through nested stacks
+	starts map[string]time.Time
+}
+
+func newProgressTracker(store estimate.Store) *progressTracker {
+	return &progressTracker{
+		store:  store,
+		starts: make(map[string]time.Time),
+	}
+}
+
+// loadDependencies reads the stack's template the first time it is called.
+// If the template can't be read, resources are estimated as if they had no dependencies.
+func (p *progressTracker) loadDependencies(ctx context.Context, u *UI, stackName string) {
+	if p.depsLoaded {
+		return
+	}
+	p.depsLoaded = true
+
+	template, err := u.cfnClient.GetTemplate(ctx, stackName, "")
+	if err != nil {
+		return
+	}
+
+	p.deps, _ = estimate.Dependencies(template)
+}
+
+// observe records the duration of each resource which has finished since it was seen in progress
+func (p *progressTracker) observe(node *stackNode, path string) {
+	for _, r := range node.resources {
+		key := path + r.logicalID
+
+		if r.nested != nil {
+			p.observe(r.nested, key+"/")
+		}
+
+		res := status.Resource(r.status)
+
+		switch {
+		case res.IsInProgress() && res != "REVIEW_IN_PROGRESS":
+			if _, ok := p.starts[key]; !ok && !r.since.IsZero() {
+				p.starts[key] = r.since
+			}
+
+		case strings.HasSuffix(r.status, "_COMPLETE") && !res.IsRollback():
+			start, ok := p.starts[key]
+			if !ok || r.since.Before(start) {
+				continue
+			}
+			delete(p.starts, key)
+
+			// Recording is best effort; a broken store shouldn't interrupt the deployment
+			_ = p.store.Record(r.resourceType, statusAction(r.status), r.since.Sub(start))
+
+		default:
+			delete(p.starts, key)
+		}
+	}
+}
+
+// estimateResources lists the resources taking part in the stack's operation.
+// Nested stacks are estimated as a single resource.
+func estimateResources(node *stackNode) []estimate.Resource {
+	op := node.operation()
+	out := make([]estimate.Resource, 0, len(node.resources))
+
+	for _, r := range node.resources {
+		res := estimate.Resource{
+			LogicalID: r.logicalID,
+			Type:      r.resourceType,
+			Action:    r.action,
+			State:     estimate.Pending,
+		}
+
+		switch status.Resource(r.status).Progress(op) {
+		case status.Started:
+			res.State = estimate.Running
+			res.Since = r.since
+			res.Action = statusAction(r.status)
+		case status.Finished:
+			// The status may have been left by an earlier operation
+			if !r.since.Before(node.started) {
+				res.State = estimate.Done
+				res.Action = statusAction(r.status)
+			}
+		}
+
+		if res.State == estimate.Pending {
+			switch {
+			case op == status.OperationDelete:
+				res.Action = "delete"
+			case r.action == "":
+				// The resource isn't being changed
+				continue
+			}
+		}
+
+		out = append(out, res)
+	}
+
+	return out
+}
+
+// estimate predicts how far through its operation the stack is
+func (p *progressTracker) estimate(node *stackNode, now time.Time) estimate.Estimate {
+	return estimate.New(p.store).Estimate(estimateResources(node), p.deps, now)
+}
+
+// renderProgress renders a progress bar followed by the number of resources done and the time left
+func renderProgress(est estimate.Estimate, width int) string {
+	symbols := ActiveTheme().Symbols
+
+	barWidth := progressBarWidth
+	if width > 0 && width/3 < barWidth {
+		barWidth = width / 3
+	}
+
+	percent := est.Percent
+	if percent > 100 {
+		percent = 100
+	}
+
+	filled := int(percent / 100 * float64(barWidth))
+	bar := statusColour(complete)(strings.Repeat(symbols.ProgressDone, filled)) + console.Grey(strings.Repeat(symbols.ProgressTodo, barWidth-filled))
+
+	line := fmt.Sprintf("  %s %3.0f%% %s", bar, percent, console.Grey(fmt.Sprintf("%d/%d resources", est.Done, est.Total)))
+
+	switch {
+	case !est.Known:
+		line += console.Grey(", no timings recorded yet")
+	case est.Remaining < time.Second:
+		line += console.Grey(", almost done")
+	default:
+		line += console.Grey(fmt.Sprintf(", about %s left", formatRemaining(est.Remaining)))
+	}
+
+	return line
+}
+
+// formatRemaining rounds the time left so that it doesn't look more precise than it is
+func formatRemaining(d time.Duration) string {
+	if d >= time.Minute {
+		return d.Round(10 * time.Second).String()
+	}
+	return d.Round(time.Second).String()
+}
@@ -0,0 +1,108 @@
+package ui
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/common-fate/cloudform/estimate"
+)
+
+func TestProgressTrackerRecordsDurations(t *testing.T) {
+	store := estimate.NewMemoryStore()
+	p := newProgressTracker(store)
+	start := time.Now()
+
+	node := func(s string, since time.Time) *stackNode {
+		return &stackNode{
+			name:   "app",
+			status: "CREATE_IN_PROGRESS",
+			resources: []*resourceNode{
+				{logicalID: "Bucket", resourceType: "AWS::S3::Bucket", status: s, since: since},
+				{logicalID: "Database", resourceType: "AWS::CloudFormation::Stack", status: "CREATE_IN_PROGRESS", since: start, nested: &stackNode{
+					name:   "Database",
+					status: "CREATE_IN_PROGRESS",
+					resources: []*resourceNode{
+						{logicalID: "Table", resourceType: "AWS::DynamoDB::Table", status: s, since: since},
+					},
+				}},
+			},
+		}
+	}
+
+	p.observe(node("CREATE_IN_PROGRESS", start), "")
+	p.observe(node("CREATE_COMPLETE", start.Add(42*time.Second)), "")
+	// Seeing the same status again doesn't record it twice
+	p.observe(node("CREATE_COMPLETE", start.Add(42*time.Second)), "")
+
+	for _, resourceType := range []string{"AWS::S3::Bucket", "AWS::DynamoDB::Table"} {
+		durations, _ := store.Durations(resourceType, "create")
+		if diff := cmp.Diff([]time.Duration{42 * time.Second}, durations); diff != "" {
+			t.Errorf("%s: %s", resourceType, diff)
+		}
+	}
+
+	// Resources which weren't seen starting aren't recorded
+	durations, _ := store.Durations("AWS::CloudFormation::Stack", "create")
+	if len(durations) != 0 {
+		t.Errorf("expected no durations for the nested stack, got %v", durations)
+	}
+}
+
+func TestEstimateResources(t *testing.T) {
+	started := time.Now().Add(-time.Minute)
+
+	node := &stackNode{
+		name:    "app",
+		status:  "UPDATE_IN_PROGRESS",
+		started: started,
+		resources: []*resourceNode{
+			// Changed by an earlier update and not part of this one
+			{logicalID: "Old", resourceType: "AWS::S3::Bucket", status: "UPDATE_COMPLETE", since: started.Add(-time.Hour)},
+			// In the change set and not started yet
+			{logicalID: "Role", resourceType: "AWS::IAM::Role", status: "UPDATE_COMPLETE", since: started.Add(-time.Hour), action: "update"},
+			{logicalID: "Queue", resourceType: "AWS::SQS::Queue", status: "REVIEW_IN_PROGRESS", action: "create"},
+			{logicalID: "Function", resourceType: "AWS::Lambda::Function", status: "UPDATE_IN_PROGRESS", since: started.Add(time.Second), action: "update"},
+			{logicalID: "Topic", resourceType: "AWS::SNS::Topic", status: "CREATE_COMPLETE", since: started.Add(time.Second), action: "create"},
+		},
+	}
+
+	expected := []estimate.Resource{
+		{LogicalID: "Role", Type: "AWS::IAM::Role", Action: "update", State: estimate.Pending},
+		{LogicalID: "Queue", Type: "AWS::SQS::Queue", Action: "create", State: estimate.Pending},
+		{LogicalID: "Function", Type: "AWS::Lambda::Function", Action: "update", State: estimate.Running, Since: started.Add(time.Second)},
+		{LogicalID: "Topic", Type: "AWS::SNS::Topic", Action: "create", State: estimate.Done},
+	}
+
+	if diff := cmp.Diff(expected, estimateResources(node)); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestRenderProgress(t *testing.T) {
+	setColour(t, false)
+
+	for _, tc := range []struct {
+		est      estimate.Estimate
+		expected string
+	}{
+		{
+			est:      estimate.Estimate{Done: 1, Total: 4, Percent: 50, Remaining: 95 * time.Second, Known: true},
+			expected: "  ██████████░░░░░░░░░░  50% 1/4 resources, about 1m40s left",
+		},
+		{
+			est:      estimate.Estimate{Done: 0, Total: 2, Percent: 10},
+			expected: "  ██░░░░░░░░░░░░░░░░░░  10% 0/2 resources, no timings recorded yet",
+		},
+		{
+			est:      estimate.Estimate{Done: 2, Total: 2, Percent: 100, Known: true},
+			expected: "  ████████████████████ 100% 2/2 resources, almost done",
+		},
+	} {
+		actual := renderProgress(tc.est, 60)
+		if actual != tc.expected {
+			t.Errorf("expected %q, got %q", tc.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws-cloudformation/rain/cft/parse"
+	"github.com/common-fate/cloudform/console"
+	"github.com/common-fate/cloudform/status"
+)
+
+// SlowResourceOpts configures warnings for resources which have been in progress for a long time
+type SlowResourceOpts struct {
+	// Thresholds are how long resources of each type can be in progress before they are reported.
+	// Keys are resource types, or prefixes ending in "*" such as "Custom::*".
+	// The longest matching key is used.
+	Thresholds map[string]time.Duration
+	// DefaultThreshold applies to resource types without a threshold.
+	// If it is zero, only types with a threshold are reported.
+	DefaultThreshold time.Duration
+	// OnSlowResource is called once each time a resource passes its threshold,
+	// for example to page someone
+	OnSlowResource func(SlowResource)
+}
+
+// DefaultSlowResourceOpts are used unless WithSlowResources is given
+var DefaultSlowResourceOpts = SlowResourceOpts{
+	Thresholds: map[string]time.Duration{
+		"Custom::*":                           10 * time.Minute,
+		"AWS::CloudFormation::CustomResource": 10 * time.Minute,
+		"AWS::CloudFormation::WaitCondition":  30 * time.Minute,
+		"AWS::CloudFront::Distribution":       30 * time.Minute,
+		"AWS::RDS::DBCluster":                 45 * time.Minute,
+		"AWS::RDS::DBInstance":                45 * time.Minute,
+	},
+	DefaultThreshold: 30 * time.Minute,
+}
+
+// SlowResource is a resource which has been in progress for longer than its threshold
+type SlowResource struct {
+	StackName string
+	// LogicalID includes the logical IDs of any nested stacks, such as "Database/Table"
+	LogicalID    string
+	PhysicalID   string
+	ResourceType string
+	Status       string
+	// Since is when the resource entered its current status
+	Since     time.Time
+	Elapsed   time.Duration
+	Threshold time.Duration
+	// ServiceToken is the function or topic which handles a custom resource, if it is known
+	ServiceToken string
+	// LogGroup is the CloudWatch log group of the Lambda function
+	// which handles a custom resource, if it is known
+	LogGroup string
+}
+
+// Hint describes the slow resource and where to look for the cause
+func (s SlowResource) Hint() string {
+	hint := fmt.Sprintf("%s has been %s for %s, longer than the %s expected for %s",
+		s.LogicalID, s.Status, s.Elapsed.Truncate(time.Second), s.Threshold, s.ResourceType)
+
+	switch {
+	case s.LogGroup != "":
+		hint += fmt.Sprintf(". Check the logs in %s", s.LogGroup)
+	case s.ServiceToken != "":
+		hint += fmt.Sprintf(". It is handled by %s", s.ServiceToken)
+	}
+
+	return hint
+}
+
+// threshold returns how long a resource type can be in progress, or zero if it isn't checked
+func (o SlowResourceOpts) threshold(resourceType string) time.Duration {
+	if d, ok := o.Thresholds[resourceType]; ok {
+		return d
+	}
+
+	best := -1
+	threshold := o.DefaultThreshold
+	for pattern, d := range o.Thresholds {
+		prefix := strings.TrimSuffix(pattern, "*")
+		if prefix == pattern || !strings.HasPrefix(resourceType, prefix) {
+			continue
+		}
+		if len(prefix) > best {
+			best = len(prefix)
+			threshold = d
+		}
+	}
+
+	return threshold
+}
+
+// isCustomResource returns true for resources which are handled by a ServiceToken
+func isCustomResource(resourceType string) bool {
+	return strings.HasPrefix(resourceType, "Custom::") || resourceType == "AWS::CloudFormation::CustomResource"
+}
+
+// serviceToken is the ServiceToken property of a custom resource.
+// Either the ARN is known, or the token refers to a Lambda function in the same stack.
+type serviceToken struct {
+	arn      string
+	function string
+}
+
+// serviceTokens reads the ServiceToken of each custom resource in a template, by logical ID
+func serviceTokens(template string) map[string]serviceToken {
+	tokens := make(map[string]serviceToken)
+
+	t, err := parse.String(template)
+	if err != nil {
+		return tokens
+	}
+
+	var m map[string]interface{}
+	if t.Decode(&m) != nil {
+		return tokens
+	}
+
+	resources, _ := m["Resources"].(map[string]interface{})
+	for name, res := range resources {
+		res, _ := res.(map[string]interface{})
+		resourceType, _ := res["Type"].(string)
+		if !isCustomResource(resourceType) {
+			continue
+		}
+
+		props, _ := res["Properties"].(map[string]interface{})
+		switch token := props["ServiceToken"].(type) {
+		case string:
+			tokens[name] = serviceToken{arn: token}
+		case map[string]interface{}:
+			// Ref and Fn::GetAtt of a function both resolve to the function
+			if ref, ok := token["Ref"].(string); ok {
+				tokens[name] = serviceToken{function: ref}
+			}
+			switch att := token["Fn::GetAtt"].(type) {
+			case string:
+				tokens[name] = serviceToken{function: strings.Split(att, ".")[0]}
+			case []interface{}:
+				if len(att) > 0 {
+					if fn, ok := att[0].(string); ok {
+						tokens[name] = serviceToken{function: fn}
+					}
+				}
+			}
+		}
+	}
+
+	return tokens
+}
+
+// lambdaLogGroup returns the log group of a Lambda function, given its name or ARN.
+// An empty string is returned for ARNs of other services, such as SNS topics.
+func lambdaLogGroup(function string) string {
+	if !strings.HasPrefix(function, "arn:") {
+		return "/aws/lambda/" + function
+	}
+
+	// arn:aws:lambda:region:account:function:name[:qualifier]
+	parts := strings.Split(function, ":")
+	if len(parts) < 7 || parts[2] != "lambda" || parts[5] != "function" {
+		return ""
+	}
+	return "/aws/lambda/" + parts[6]
+}
+
+// slowTracker finds resources which have been in progress for too long
+type slowTracker struct {
+	opts SlowResourceOpts
+	// reported is the resources which OnSlowResource has been called for,
+	// keyed by their path and when they entered their status
+	reported map[string]bool
+	// tokens caches the service tokens of each stack's custom resources, by stack ID
+	tokens map[string]map[string]serviceToken
+}
+
+func newSlowTracker(opts SlowResourceOpts) *slowTracker {
+	return &slowTracker{
+		opts:     opts,
+		reported: make(map[string]bool),
+		tokens:   make(map[string]map[string]serviceToken),
+	}
+}
+
+// check marks slow resources in the tree and returns them.
+// stackID identifies the stack the tree was built from, and is used to read its template.
+func (s *slowTracker) check(ctx context.Context, u *UI, node *stackNode, stackID string, now time.Time) []SlowResource {
+	return s.checkStack(ctx, u, node, node.name, stackID, "", now)
+}
+
+func (s *slowTracker) checkStack(ctx context.Context, u *UI, node *stackNode, stackName, stackID, path string, now time.Time) []SlowResource {
+	slow := make([]SlowResource, 0)
+	op := node.operation()
+
+	for _, r := range node.resources {
+		logicalID := path + r.logicalID
+
+		if r.nested != nil && r.physicalID != "" {
+			slow = append(slow, s.checkStack(ctx, u, r.nested, stackName, r.physicalID, logicalID+"/", now)...)
+		}
+
+		if status.Resource(r.status).Category(op) != status.InProgress || r.since.IsZero() {
+			continue
+		}
+
+		threshold := s.opts.threshold(r.resourceType)
+		elapsed := now.Sub(r.since)
+		if threshold <= 0 || elapsed < threshold {
+			continue
+		}
+
+		r.slow = true
+
+		res := SlowResource{
+			StackName:    stackName,
+			LogicalID:    logicalID,
+			PhysicalID:   r.physicalID,
+			ResourceType: r.resourceType,
+			Status:       r.status,
+			Since:        r.since,
+			Elapsed:      elapsed,
+			Threshold:    threshold,
+		}
+
+		if isCustomResource(r.resourceType) {
+			res.ServiceToken, res.LogGroup = s.handler(ctx, u, node, stackID, r.logicalID)
+		}
+
+		slow = append(slow, res)
+
+		key := fmt.Sprintf("%s@%s", logicalID, r.since)
+		if !s.reported[key] {
+			s.reported[key] = true
+			if s.opts.OnSlowResource != nil {
+				s.opts.OnSlowResource(res)
+			}
+		}
+	}
+
+	return slow
+}
+
+// handler returns the service token and log group of a custom resource.
+// The stack's template is read the first time one of its custom resources is slow.
+func (s *slowTracker) handler(ctx context.Context, u *UI, node *stackNode, stackID, logicalID string) (string, string) {
+	tokens, ok := s.tokens[stackID]
+	if !ok {
+		// Errors just mean we can't give a hint
+		template, err := u.cfnClient.GetTemplate(ctx, stackID, "")
+		if err == nil {
+			tokens = serviceTokens(template)
+		}
+		s.tokens[stackID] = tokens
+	}
+
+	token, ok := tokens[logicalID]
+	if !ok {
+		return "", ""
+	}
+
+	if token.arn != "" {
+		return token.arn, lambdaLogGroup(token.arn)
+	}
+
+	for _, r := range node.resources {
+		if r.logicalID == token.function && r.physicalID != "" {
+			return r.physicalID, lambdaLogGroup(r.physicalID)
+		}
+	}
+
+	return "", ""
+}
+
+// renderSlowResources renders a warning for each slow resource
+func renderSlowResources(slow []SlowResource) string {
+	if len(slow) == 0 {
+		return ""
+	}
+
+	out := strings.Builder{}
+	out.WriteString(console.Yellow("Warnings:\n"))
+	for _, s := range slow {
+		out.WriteString(fmt.Sprintf("  - %s\n", s.Hint()))
+	}
+
+	return out.String()
+}
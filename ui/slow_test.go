@@ -0,0 +1,140 @@
+package ui
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSlowResourceThreshold(t *testing.T) {
+	opts := SlowResourceOpts{
+		Thresholds: map[string]time.Duration{
+			"Custom::*":              10 * time.Minute,
+			"Custom::Slow*":          time.Hour,
+			"AWS::Lambda::Function":  time.Minute,
+			"AWS::CloudFront::Dist*": 0,
+		},
+		DefaultThreshold: 30 * time.Minute,
+	}
+
+	for resourceType, expected := range map[string]time.Duration{
+		"Custom::Thing":                 10 * time.Minute,
+		"Custom::SlowThing":             time.Hour,
+		"AWS::Lambda::Function":         time.Minute,
+		"AWS::S3::Bucket":               30 * time.Minute,
+		"AWS::CloudFront::Distribution": 0,
+	} {
+		if actual := opts.threshold(resourceType); actual != expected {
+			t.Errorf("%s: expected %s, got %s", resourceType, expected, actual)
+		}
+	}
+}
+
+func TestServiceTokens(t *testing.T) {
+	tokens := serviceTokens(`
+Resources:
+  Handler:
+    Type: AWS::Lambda::Function
+  ByGetAtt:
+    Type: Custom::Thing
+    Properties:
+      ServiceToken: !GetAtt Handler.Arn
+  ByRef:
+    Type: AWS::CloudFormation::CustomResource
+    Properties:
+      ServiceToken: !Ref Handler
+  ByArn:
+    Type: Custom::Thing
+    Properties:
+      ServiceToken: arn:aws:lambda:us-east-1:123456789012:function:shared-handler
+`)
+
+	expected := map[string]serviceToken{
+		"ByGetAtt": {function: "Handler"},
+		"ByRef":    {function: "Handler"},
+		"ByArn":    {arn: "arn:aws:lambda:us-east-1:123456789012:function:shared-handler"},
+	}
+
+	if diff := cmp.Diff(expected, tokens, cmp.AllowUnexported(serviceToken{})); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestLambdaLogGroup(t *testing.T) {
+	for function, expected := range map[string]string{
+		"my-function": "/aws/lambda/my-function",
+		"arn:aws:lambda:us-east-1:123456789012:function:my-function":        "/aws/lambda/my-function",
+		"arn:aws:lambda:us-east-1:123456789012:function:my-function:live":   "/aws/lambda/my-function",
+		"arn:aws:sns:us-east-1:123456789012:custom-resources":               "",
+		"arn:aws:lambda:us-east-1:123456789012:layer:my-layer:1":            "",
+		"arn:aws-cn:lambda:cn-north-1:123456789012:function:china-function": "/aws/lambda/china-function",
+	} {
+		if actual := lambdaLogGroup(function); actual != expected {
+			t.Errorf("%s: expected %q, got %q", function, expected, actual)
+		}
+	}
+}
+
+func TestSlowTrackerCheck(t *testing.T) {
+	now := time.Now()
+
+	var reported []SlowResource
+	s := newSlowTracker(SlowResourceOpts{
+		Thresholds: map[string]time.Duration{"Custom::*": 10 * time.Minute},
+		OnSlowResource: func(r SlowResource) {
+			reported = append(reported, r)
+		},
+	})
+	// Avoid reading the templates from CloudFormation
+	s.tokens["app-id"] = map[string]serviceToken{"Seed": {function: "Handler"}}
+
+	node := &stackNode{
+		name:   "app",
+		status: "CREATE_IN_PROGRESS",
+		resources: []*resourceNode{
+			{logicalID: "Handler", physicalID: "app-handler-abc123", resourceType: "AWS::Lambda::Function", status: "CREATE_COMPLETE"},
+			{logicalID: "Seed", resourceType: "Custom::Seed", status: "CREATE_IN_PROGRESS", since: now.Add(-25 * time.Minute)},
+			{logicalID: "Recent", resourceType: "Custom::Seed", status: "CREATE_IN_PROGRESS", since: now.Add(-time.Minute)},
+			// Resources without a threshold aren't checked
+			{logicalID: "Bucket", resourceType: "AWS::S3::Bucket", status: "CREATE_IN_PROGRESS", since: now.Add(-time.Hour)},
+		},
+	}
+
+	slow := s.check(context.Background(), nil, node, "app-id", now)
+	// A resource which is still slow isn't reported again
+	s.check(context.Background(), nil, node, "app-id", now.Add(time.Minute))
+
+	expected := []SlowResource{
+		{
+			StackName:    "app",
+			LogicalID:    "Seed",
+			ResourceType: "Custom::Seed",
+			Status:       "CREATE_IN_PROGRESS",
+			Since:        now.Add(-25 * time.Minute),
+			Elapsed:      25 * time.Minute,
+			Threshold:    10 * time.Minute,
+			ServiceToken: "app-handler-abc123",
+			LogGroup:     "/aws/lambda/app-handler-abc123",
+		},
+	}
+
+	if diff := cmp.Diff(expected, slow); diff != "" {
+		t.Error(diff)
+	}
+	if diff := cmp.Diff(expected, reported); diff != "" {
+		t.Error(diff)
+	}
+
+	for _, r := range node.resources {
+		if r.slow != (r.logicalID == "Seed") {
+			t.Errorf("%s: unexpected slow mark %v", r.logicalID, r.slow)
+		}
+	}
+
+	hint := "Seed has been CREATE_IN_PROGRESS for 25m0s, longer than the 10m0s expected for Custom::Seed. Check the logs in /aws/lambda/app-handler-abc123"
+	if actual := slow[0].Hint(); actual != hint {
+		t.Errorf("expected %q, got %q", hint, actual)
+	}
+}
@@ -3,7 +3,6 @@ package ui
 import (
 	"context"
 	"fmt"
-	"sort"
 	"strings"
 	"time"
 
@@ -14,27 +13,37 @@ import (
 	"github.com/common-fate/cloudform/cfn"
 	"github.com/common-fate/cloudform/console"
 	"github.com/common-fate/cloudform/console/spinner"
+	"github.com/common-fate/cloudform/estimate"
+	"github.com/common-fate/cloudform/status"
 )
 
-func statusIsSettled(status string) bool {
-	if strings.HasSuffix(status, "_COMPLETE") || strings.HasSuffix(status, "_FAILED") {
-		return true
-	}
-	return false
+func statusIsSettled(stackStatus string) bool {
+	return status.SettleRules{}.Outcome(status.Stack(stackStatus)).IsSettled()
 }
 
 // StackHasSettled returns whether a given status represents
-// a stack that has settled, i.e. is not updating
+// a stack that has settled, i.e. is not updating.
+// Stacks which are waiting for a change set to be reviewed, or which
+// are cleaning up after an update, have settled.
 func StackHasSettled(stack types.Stack) bool {
 	return statusIsSettled(string(stack.StackStatus))
 }
 
-func (u *UI) stackResourceStatuses(ctx context.Context, stack types.Stack) (string, []string) {
+// buildStackNode collects the status of every resource in a stack, including nested stacks,
+// along with any failure messages
+func (u *UI) buildStackNode(ctx context.Context, stack types.Stack, name string) (*stackNode, []string) {
 	stackName := ptr.ToString(stack.StackName)
 
+	node := &stackNode{
+		name:    name,
+		status:  string(stack.StackStatus),
+		started: operationStart(stack),
+	}
+
+	op := node.operation()
 	statuses := make(map[string]string)
 	messages := make([]string, 0)
-	nested := make(map[string]string)
+	resources := make(map[string]*resourceNode)
 
 	// Get changeset details if possible
 	changeset, err := u.cfnClient.GetChangeSet(ctx, stackName, ptr.ToString(stack.ChangeSetId))
@@ -43,27 +52,51 @@ func (u *UI) stackResourceStatuses(ctx context.Context, stack types.Stack) (stri
 			resourceID := ptr.ToString(change.ResourceChange.LogicalResourceId)
 			statuses[resourceID] = "REVIEW_IN_PROGRESS"
 
+			r := &resourceNode{
+				logicalID:    resourceID,
+				resourceType: ptr.ToString(change.ResourceChange.ResourceType),
+				status:       "REVIEW_IN_PROGRESS",
+				action:       changeAction(change.ResourceChange.Action),
+			}
+
 			// Store nested stacks
-			if ptr.ToString(change.ResourceChange.ResourceType) == "AWS::CloudFormation::Stack" {
-				nested[resourceID] = fmt.Sprintf("%s: %s", console.Yellow(fmt.Sprintf("Stack %s", resourceID)), console.Grey("PENDING"))
+			if r.resourceType == "AWS::CloudFormation::Stack" {
+				r.nested = &stackNode{name: resourceID, status: "PENDING"}
 			}
+
+			resources[resourceID] = r
 		}
 	}
 
 	// We ignore errors because it just means we'll list no resources
-	resources, _ := u.cfnClient.GetStackResources(ctx, stackName)
-	for _, resource := range resources {
+	stackResources, _ := u.cfnClient.GetStackResources(ctx, stackName)
+	for _, resource := range stackResources {
 		resourceID := ptr.ToString(resource.LogicalResourceId)
 
-		status := string(resource.ResourceStatus)
-		rep := mapStatus(status)
+		resourceStatus := string(resource.ResourceStatus)
+		rep := mapResourceStatus(resourceStatus, op)
+
+		statuses[resourceID] = resourceStatus
 
-		statuses[resourceID] = status
+		r := &resourceNode{
+			logicalID:    resourceID,
+			physicalID:   ptr.ToString(resource.PhysicalResourceId),
+			resourceType: ptr.ToString(resource.ResourceType),
+			status:       resourceStatus,
+			reason:       ptr.ToString(resource.ResourceStatusReason),
+		}
+		if resource.Timestamp != nil {
+			r.since = *resource.Timestamp
+		}
+		if existing, ok := resources[resourceID]; ok {
+			r.action = existing.action
+		}
+		resources[resourceID] = r
 
 		// Store messages
 		if resource.ResourceStatusReason != nil && rep.category == failed {
 			msg := ptr.ToString(resource.ResourceStatusReason)
-			colour := statusColour[rep.category]
+			colour := statusColour(rep.category)
 
 			if msg != "Resource creation cancelled" {
 				id := resourceID
@@ -76,11 +109,11 @@ func (u *UI) stackResourceStatuses(ctx context.Context, stack types.Stack) (stri
 		}
 
 		// Store nested stacks
-		if ptr.ToString(resource.ResourceType) == "AWS::CloudFormation::Stack" {
+		if r.resourceType == "AWS::CloudFormation::Stack" {
 			stack, err := u.cfnClient.GetStack(ctx, ptr.ToString(resource.PhysicalResourceId))
 			if err == nil {
-				rs, rMessages := u.GetStackOutput(ctx, stack)
-				nested[resourceID] = rs
+				nested, rMessages := u.buildStackNode(ctx, stack, resourceID)
+				r.nested = nested
 				for _, rMessage := range rMessages {
 					messages = append(messages, fmt.Sprintf("%s%s", console.Yellow(fmt.Sprintf("%s/", resourceID)), rMessage))
 				}
@@ -88,50 +121,33 @@ func (u *UI) stackResourceStatuses(ctx context.Context, stack types.Stack) (stri
 		}
 	}
 
-	// Build the output
+	node.summary = resourceSummary(node.status, statuses)
+
+	for _, r := range resources {
+		node.resources = append(node.resources, r)
+	}
+	sortResources(node.resources, op)
+
+	return node, messages
+}
+
+// resourceSummary counts the resources which are pending and in progress
+func resourceSummary(stackStatus string, statuses map[string]string) string {
+	inProgressColour := statusColour(inProgress)
+
 	out := strings.Builder{}
-	stackStatus := string(stack.StackStatus)
-	if strings.HasSuffix(stackStatus, "_IN_PROGRESS") {
+	if stack := status.Stack(stackStatus); stack.IsInProgress() {
+		op := stack.Operation()
 		total := len(statuses)
 		complete := 0
 		inProgress := 0
 
-		for _, status := range statuses {
-
-			switch stackStatus {
-			case
-				"CREATE_IN_PROGRESS",
-				"UPDATE_IN_PROGRESS",
-				"UPDATE_COMPLETE_CLEANUP_IN_PROGRESS",
-				"REVIEW_IN_PROGRESS",
-				"IMPORT_IN_PROGRESS":
-				switch status {
-				case "CREATE_COMPLETE", "CREATE_FAILED", "UPDATE_COMPLETE", "UPDATE_FAILED", "IMPORT_COMPLETE", "IMPORT_FAILED":
-					complete++
-				case "CREATE_IN_PROGRESS", "UPDATE_IN_PROGRESS", "IMPORT_IN_PROGRESS":
-					inProgress++
-				}
-
-			case
-				"DELETE_IN_PROGRESS":
-				switch status {
-				case "DELETE_COMPLETE", "DELETE_FAILED", "DELETE_SKIPPED":
-					complete++
-				case "DELETE_IN_PROGRESS":
-					inProgress++
-				}
-
-			case
-				"ROLLBACK_IN_PROGRESS",
-				"UPDATE_ROLLBACK_IN_PROGRESS",
-				"UPDATE_ROLLBACK_COMPLETE_CLEANUP_IN_PROGRESS",
-				"IMPORT_ROLLBACK_IN_PROGRESS":
-				switch status {
-				case "DELETE_COMPLETE", "DELETE_FAILED", "DELETE_SKIPPED", "IMPORT_ROLLBACK_COMPLETE", "IMPORT_ROLLBACK_FAILED":
-					complete++
-				case "DELETE_IN_PROGRESS", "IMPORT_ROLLBACK_IN_PROGRESS":
-					inProgress++
-				}
+		for _, s := range statuses {
+			switch status.Resource(s).Progress(op) {
+			case status.Finished:
+				complete++
+			case status.Started:
+				inProgress++
 			}
 		}
 
@@ -152,7 +168,7 @@ func (u *UI) stackResourceStatuses(ctx context.Context, stack types.Stack) (stri
 			if inProgress == 1 {
 				word = "resource"
 			}
-			parts = append(parts, console.Blue(fmt.Sprintf("%d %s in progress", inProgress, word)))
+			parts = append(parts, inProgressColour(fmt.Sprintf("%d %s in progress", inProgress, word)))
 		}
 
 		if len(parts) > 0 {
@@ -161,115 +177,157 @@ func (u *UI) stackResourceStatuses(ctx context.Context, stack types.Stack) (stri
 		}
 	}
 
-	out.WriteString("\n")
-
-	// Append nested stacks to the output
-	names := make([]string, 0)
-	for name := range nested {
-		names = append(names, name)
-	}
-	sort.Strings(names)
-
-	for _, name := range names {
-		other := nested[name]
-		parts := strings.Split(strings.TrimSpace(other), "\n")
-		for _, part := range parts {
-			out.WriteString(fmt.Sprintf("  - %s\n", part))
-		}
-	}
+	return out.String()
 
-	return out.String(), messages
 }
 
 type UI struct {
 	cfnClient *cfn.Cfn
+	term      *console.Terminal
+	spinner   *spinner.Spinner
+	durations estimate.Store
+	slow      SlowResourceOpts
 }
 
-// New creates a new UI.
-func New(cfg aws.Config) *UI {
-	c := cfn.New(cfg)
-	return &UI{c}
-}
-
-// GetStackOutput returns a pretty representation of a CloudFormation stack's status
-func (u *UI) GetStackOutput(ctx context.Context, stack types.Stack) (string, []string) {
-	out := strings.Builder{}
-
-	stackStatus := string(stack.StackStatus)
-	stackName := ptr.ToString(stack.StackName)
-
-	rs, messages := u.stackResourceStatuses(ctx, stack)
+// Option configures a UI
+type Option func(*UI)
 
-	out.WriteString(fmt.Sprintf("%s: %s %s", console.Yellow(fmt.Sprintf("Stack %s", stackName)), ColouriseStatus(stackStatus), rs))
+// WithTerminal sets the terminal the UI renders to. The default is console.Default.
+func WithTerminal(t *console.Terminal) Option {
+	return func(u *UI) {
+		u.term = t
+	}
+}
 
-	return strings.TrimSpace(out.String()), messages
+// WithDurationStore sets where the time taken by each resource is recorded,
+// which is used to estimate how long operations have left.
+// The default is a file in the user's cache directory.
+func WithDurationStore(s estimate.Store) Option {
+	return func(u *UI) {
+		u.durations = s
+	}
 }
 
-// WaitForStackToSettle blocks excute until a stack has finished updating
-// and then returns its status
-func (u *UI) WaitForStackToSettle(ctx context.Context, stackName string) (string, []string) {
-	// Start the timer
-	spinner.StartTimer("")
+// WithSlowResources sets how long resources can be in progress before they are reported as slow.
+// The default is DefaultSlowResourceOpts.
+func WithSlowResources(opts SlowResourceOpts) Option {
+	return func(u *UI) {
+		u.slow = opts
+	}
+}
 
-	stackID := stackName
+// New creates a new UI.
+// Unless WithDurationStore is given, the time taken by each resource is recorded
+// in a file in the user's cache directory.
+func New(cfg aws.Config, opts ...Option) *UI {
+	u := &UI{
+		cfnClient: cfn.New(cfg),
+		term:      console.Default,
+		slow:      DefaultSlowResourceOpts,
+	}
 
-	collectedMessages := make(map[string]bool)
+	for _, o := range opts {
+		o(u)
+	}
 
-	out := strings.Builder{}
-	outStr := ""
-	lastOutput := ""
+	if u.durations == nil {
+		path, err := estimate.DefaultFileStorePath()
+		if err == nil {
+			u.durations = estimate.NewFileStore(path)
+		} else {
+			u.durations = estimate.NewMemoryStore()
+		}
+	}
 
-	for {
-		out.Reset()
+	u.spinner = spinner.New(spinner.WithTerminal(u.term))
 
-		stack, err := u.cfnClient.GetStack(ctx, stackID)
-		if err != nil {
-			panic(Errorf(err, "operation failed"))
-		}
+	return u
+}
 
-		// Refresh the stack ID so we can deal with deleted stacks ok
-		stackID = ptr.ToString(stack.StackId)
+// Terminal returns the terminal the UI renders to
+func (u *UI) Terminal() *console.Terminal {
+	return u.term
+}
 
-		output, messages := u.GetStackOutput(ctx, stack)
+// GetStackOutput returns a pretty representation of a CloudFormation stack's status.
+// Each resource of a stack which is in progress is listed, with nested stacks shown as a tree.
+// The output is folded so that it fits within the height of the console.
+func (u *UI) GetStackOutput(ctx context.Context, stack types.Stack) (string, []string) {
+	return u.stackOutput(ctx, stack, nil, nil)
+}
 
-		// Send the output first
-		out.WriteString(output)
-		out.WriteString("\n")
+// stackOutput renders a stack like GetStackOutput. If progress is set, the time taken by
+// resources is recorded and a progress bar is shown below the stack's status.
+// If slow is set, resources which have been in progress for too long are marked and
+// a warning is shown for each of them below the tree.
+// Stacks with a monitoring period show a countdown once their resources have settled.
+func (u *UI) stackOutput(ctx context.Context, stack types.Stack, progress *progressTracker, slow *slowTracker) (string, []string) {
+	stackName := ptr.ToString(stack.StackName)
+	node, messages := u.buildStackNode(ctx, stack, stackName)
 
-		if len(messages) > 0 {
-			out.WriteString(console.Yellow("Messages:\n"))
-			for _, message := range messages {
-				collectedMessages[message] = true
-				out.WriteString(fmt.Sprintf("  - %s\n", message))
-			}
-		}
+	width, height := u.term.Size()
+	now := time.Now()
 
-		outStr = out.String()
+	if !status.Stack(node.status).IsInProgress() {
+		return renderStackTree(node, width, maxTreeLines(height), now), messages
+	}
 
-		spinner.Pause()
-		console.ClearLines(console.CountLines(lastOutput))
-		if console.IsTTY {
-			fmt.Print(outStr)
+	maxLines := maxTreeLines(height)
+	reserve := func(lines int) {
+		if maxLines > lines {
+			maxLines -= lines
 		}
-		lastOutput = outStr
-		spinner.Resume()
+	}
 
-		// Check to see if we've finished
-		if StackHasSettled(stack) {
-			spinner.StopTimer()
+	warnings := ""
+	if slow != nil {
+		warnings = renderSlowResources(slow.check(ctx, u, node, ptr.ToString(stack.StackId), now))
+		reserve(strings.Count(warnings, "\n"))
+	}
 
-			console.ClearLines(console.CountLines(lastOutput))
+	bar := ""
+	if progress != nil {
+		progress.observe(node, "")
+		progress.loadDependencies(ctx, u, stackName)
+		bar = renderProgress(progress.estimate(node, now), width)
+		reserve(1)
+	}
 
-			messages := make([]string, 0)
-			for message := range collectedMessages {
-				messages = append(messages, message)
-			}
+	// Once the resources have settled, CloudFormation watches the rollback triggers
+	// for the monitoring period before finishing, which is shown in place of the progress bar
+	if remaining, ok := monitoringRemaining(stack, node, now); ok {
+		if bar == "" {
+			reserve(1)
+		}
+		bar = renderMonitoring(stack, remaining)
+	}
 
-			return string(stack.StackStatus), messages
+	out := renderStackTree(node, width, maxLines, now)
+	if bar != "" {
+		header, tree, _ := strings.Cut(out, "\n")
+		out = header + "\n" + bar
+		if tree != "" {
+			out += "\n" + tree
 		}
+	}
+	if warnings != "" {
+		out += "\n" + strings.TrimSuffix(warnings, "\n")
+	}
+
+	return out, messages
+}
 
-		time.Sleep(time.Second * 2)
+// WaitForStackToSettle blocks excute until a stack has finished updating
+// and then returns its status.
+// If ctx is cancelled, the last known status is returned.
+// It panics if the stack can't be described; use WatchStacks to receive an error instead.
+func (u *UI) WaitForStackToSettle(ctx context.Context, stackName string) (string, []string) {
+	results, err := u.WatchStacks(ctx, []string{stackName}, WatchOpts{})
+	if err != nil && ctx.Err() == nil {
+		panic(err)
 	}
+
+	return results[0].Status, results[0].Messages
 }
 
 // GetStackSummary returns a string representation of an existing stack.
@@ -307,7 +365,7 @@ func (u *UI) GetStackSummary(ctx context.Context, stack types.Stack, long bool)
 		for _, resource := range resources {
 			out.WriteString(fmt.Sprintf("    %s: %s\n",
 				console.Yellow(ptr.ToString(resource.LogicalResourceId)),
-				ColouriseStatus(string(resource.ResourceStatus)),
+				colouriseResourceStatus(string(resource.ResourceStatus), status.Stack(stackStatus).Operation()),
 			))
 
 			if ptr.ToString(resource.ResourceType) == "AWS::CloudFormation::Stack" {
@@ -0,0 +1,383 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/common-fate/cloudform/console"
+	"gopkg.in/yaml.v3"
+)
+
+// Theme sets the colours and symbols used to display stack statuses and template diffs.
+// Colours are space separated lists of names understood by console.NewStyle, such as "bold red".
+type Theme struct {
+	Name string `yaml:"name"`
+	// Extends is the name of a built in theme which empty fields are copied from.
+	// Themes which don't extend another theme extend the default theme.
+	Extends string       `yaml:"extends"`
+	Colours ThemeColours `yaml:"colours"`
+	Symbols ThemeSymbols `yaml:"symbols"`
+}
+
+// ThemeColours are the colours of each kind of status and diff line
+type ThemeColours struct {
+	Failed     string `yaml:"failed"`
+	Complete   string `yaml:"complete"`
+	InProgress string `yaml:"inProgress"`
+	Pending    string `yaml:"pending"`
+
+	Added    string `yaml:"added"`
+	Removed  string `yaml:"removed"`
+	Changed  string `yaml:"changed"`
+	Involved string `yaml:"involved"`
+	Same     string `yaml:"same"`
+}
+
+// ThemeSymbols are the symbols shown next to each kind of status,
+// the symbols the progress bar is drawn with and the dashboard's pane rules and help
+type ThemeSymbols struct {
+	Failed     string `yaml:"failed"`
+	Complete   string `yaml:"complete"`
+	InProgress string `yaml:"inProgress"`
+	Pending    string `yaml:"pending"`
+
+	ProgressDone string `yaml:"progressDone"`
+	ProgressTodo string `yaml:"progressTodo"`
+
+	// Rule is repeated to draw the line along the top of each dashboard pane
+	Rule string `yaml:"rule"`
+	// ScrollKeys names the keys which scroll the dashboard in its help line
+	ScrollKeys string `yaml:"scrollKeys"`
+}
+
+// DefaultTheme is the theme used unless SetTheme is called
+var DefaultTheme = Theme{
+	Name: "default",
+	Colours: ThemeColours{
+		Failed:     "light-red",
+		Complete:   "green",
+		InProgress: "blue",
+		Pending:    "plain",
+		Added:      "green",
+		Removed:    "light-red",
+		Changed:    "blue",
+		Involved:   "grey",
+		Same:       "plain",
+	},
+	Symbols: ThemeSymbols{
+		Failed:     "x",
+		Complete:   "✓",
+		InProgress: "o",
+		Pending:    ".",
+
+		ProgressDone: "█",
+		ProgressTodo: "░",
+
+		Rule:       "─",
+		ScrollKeys: "↑/↓",
+	},
+}
+
+// Themes are the built in themes, by name
+var Themes = map[string]Theme{
+	"default": DefaultTheme,
+	// high-contrast avoids relying on telling red from green,
+	// using bold colours and a distinct symbol for every status
+	"high-contrast": {
+		Name: "high-contrast",
+		Colours: ThemeColours{
+			Failed:     "bold light-magenta",
+			Complete:   "bold light-cyan",
+			InProgress: "bold light-yellow",
+			Pending:    "plain",
+			Added:      "bold light-cyan",
+			Removed:    "bold light-magenta",
+			Changed:    "bold light-yellow",
+			Involved:   "plain",
+			Same:       "plain",
+		},
+		Symbols: ThemeSymbols{
+			Failed:     "✗",
+			Complete:   "✓",
+			InProgress: "●",
+			Pending:    "○",
+
+			ProgressDone: "█",
+			ProgressTodo: "░",
+
+			Rule:       "─",
+			ScrollKeys: "↑/↓",
+		},
+	},
+	// monochrome uses no colours, only making failures bold
+	"monochrome": {
+		Name: "monochrome",
+		Colours: ThemeColours{
+			Failed:     "bold",
+			Complete:   "plain",
+			InProgress: "plain",
+			Pending:    "plain",
+			Added:      "plain",
+			Removed:    "plain",
+			Changed:    "plain",
+			Involved:   "plain",
+			Same:       "plain",
+		},
+		Symbols: DefaultTheme.Symbols,
+	},
+	// ascii has the default colours but only uses ASCII symbols,
+	// for consoles which can't display unicode
+	"ascii": {
+		Name:    "ascii",
+		Colours: DefaultTheme.Colours,
+		Symbols: ThemeSymbols{
+			Failed:     "x",
+			Complete:   "+",
+			InProgress: "o",
+			Pending:    ".",
+
+			ProgressDone: "#",
+			ProgressTodo: "-",
+
+			Rule:       "-",
+			ScrollKeys: "up/down",
+		},
+	},
+}
+
+// compiledTheme is a theme whose colours have been parsed
+type compiledTheme struct {
+	theme   Theme
+	status  map[statusCategory]func(...interface{}) string
+	symbols map[statusCategory]string
+
+	added, removed, changed, involved, same func(...interface{}) string
+}
+
+var (
+	themeMu     sync.RWMutex
+	activeTheme = mustCompileTheme(DefaultTheme)
+)
+
+// SetTheme changes the theme used by all UIs.
+// An error is returned if the theme contains an unknown colour.
+func SetTheme(t Theme) error {
+	ct, err := compileTheme(t)
+	if err != nil {
+		return err
+	}
+
+	themeMu.Lock()
+	activeTheme = ct
+	themeMu.Unlock()
+
+	return nil
+}
+
+// ActiveTheme returns the theme currently in use
+func ActiveTheme() Theme {
+	return currentTheme().theme
+}
+
+func currentTheme() *compiledTheme {
+	themeMu.RLock()
+	defer themeMu.RUnlock()
+
+	return activeTheme
+}
+
+// resolve fills empty fields of t from the theme it extends
+func (t Theme) resolve() (Theme, error) {
+	name := t.Extends
+	if name == "" {
+		name = DefaultTheme.Name
+	}
+
+	base, ok := Themes[name]
+	if !ok {
+		return t, fmt.Errorf("theme '%s' extends unknown theme '%s'", t.Name, name)
+	}
+
+	fill := func(s *string, from string) {
+		if *s == "" {
+			*s = from
+		}
+	}
+
+	fill(&t.Colours.Failed, base.Colours.Failed)
+	fill(&t.Colours.Complete, base.Colours.Complete)
+	fill(&t.Colours.InProgress, base.Colours.InProgress)
+	fill(&t.Colours.Pending, base.Colours.Pending)
+	fill(&t.Colours.Added, base.Colours.Added)
+	fill(&t.Colours.Removed, base.Colours.Removed)
+	fill(&t.Colours.Changed, base.Colours.Changed)
+	fill(&t.Colours.Involved, base.Colours.Involved)
+	fill(&t.Colours.Same, base.Colours.Same)
+
+	fill(&t.Symbols.Failed, base.Symbols.Failed)
+	fill(&t.Symbols.Complete, base.Symbols.Complete)
+	fill(&t.Symbols.InProgress, base.Symbols.InProgress)
+	fill(&t.Symbols.Pending, base.Symbols.Pending)
+	fill(&t.Symbols.ProgressDone, base.Symbols.ProgressDone)
+	fill(&t.Symbols.ProgressTodo, base.Symbols.ProgressTodo)
+	fill(&t.Symbols.Rule, base.Symbols.Rule)
+	fill(&t.Symbols.ScrollKeys, base.Symbols.ScrollKeys)
+
+	return t, nil
+}
+
+func compileTheme(t Theme) (*compiledTheme, error) {
+	t, err := t.resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	ct := &compiledTheme{
+		theme:  t,
+		status: make(map[statusCategory]func(...interface{}) string),
+		symbols: map[statusCategory]string{
+			failed:     t.Symbols.Failed,
+			complete:   t.Symbols.Complete,
+			inProgress: t.Symbols.InProgress,
+			pending:    t.Symbols.Pending,
+		},
+	}
+
+	styles := []struct {
+		spec string
+		dest *func(...interface{}) string
+	}{
+		{t.Colours.Added, &ct.added},
+		{t.Colours.Removed, &ct.removed},
+		{t.Colours.Changed, &ct.changed},
+		{t.Colours.Involved, &ct.involved},
+		{t.Colours.Same, &ct.same},
+	}
+
+	for category, spec := range map[statusCategory]string{
+		failed:     t.Colours.Failed,
+		complete:   t.Colours.Complete,
+		inProgress: t.Colours.InProgress,
+		pending:    t.Colours.Pending,
+	} {
+		f, err := console.NewStyle(spec)
+		if err != nil {
+			return nil, fmt.Errorf("theme '%s': %w", t.Name, err)
+		}
+		ct.status[category] = f
+	}
+
+	for _, s := range styles {
+		f, err := console.NewStyle(s.spec)
+		if err != nil {
+			return nil, fmt.Errorf("theme '%s': %w", t.Name, err)
+		}
+		*s.dest = f
+	}
+
+	return ct, nil
+}
+
+func mustCompileTheme(t Theme) *compiledTheme {
+	ct, err := compileTheme(t)
+	if err != nil {
+		panic(err)
+	}
+	return ct
+}
+
+// themeFile is the format of a file of user themes
+type themeFile struct {
+	Themes []Theme `yaml:"themes"`
+}
+
+// ParseThemes parses user themes from YAML, such as:
+//
+//	themes:
+//	  - name: deuteranopia
+//	    extends: high-contrast
+//	    colours:
+//	      complete: bold blue
+//	    symbols:
+//	      inProgress: "~"
+func ParseThemes(data []byte) (map[string]Theme, error) {
+	var f themeFile
+	err := yaml.Unmarshal(data, &f)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]Theme)
+	for i, t := range f.Themes {
+		if t.Name == "" {
+			return nil, fmt.Errorf("theme %d has no name", i+1)
+		}
+
+		_, err = compileTheme(t)
+		if err != nil {
+			return nil, err
+		}
+
+		out[t.Name] = t
+	}
+
+	return out, nil
+}
+
+// LoadThemes reads user themes from a YAML file. See ParseThemes.
+func LoadThemes(path string) (map[string]Theme, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseThemes(data)
+}
+
+// ThemeConfigPath returns the default location of the user themes file
+func ThemeConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "cloudform", "themes.yaml"), nil
+}
+
+// FindTheme returns the named theme, looking in the user themes file at path first
+// and then the built in themes. The themes file is optional; if path is empty,
+// ThemeConfigPath is used.
+func FindTheme(name, path string) (Theme, error) {
+	if path == "" {
+		var err error
+		path, err = ThemeConfigPath()
+		if err != nil {
+			path = ""
+		}
+	}
+
+	if path != "" {
+		user, err := LoadThemes(path)
+		if err != nil && !os.IsNotExist(err) {
+			return Theme{}, err
+		}
+		if t, ok := user[name]; ok {
+			return t, nil
+		}
+	}
+
+	if t, ok := Themes[name]; ok {
+		return t, nil
+	}
+
+	names := make([]string, 0, len(Themes))
+	for n := range Themes {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	return Theme{}, fmt.Errorf("unknown theme '%s', the built in themes are %v", name, names)
+}
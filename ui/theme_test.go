@@ -0,0 +1,74 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/common-fate/cloudform/console"
+)
+
+func TestParseThemes(t *testing.T) {
+	themes, err := ParseThemes([]byte(`
+themes:
+  - name: deuteranopia
+    extends: high-contrast
+    colours:
+      complete: bold blue
+    symbols:
+      inProgress: "~"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	theme, err := themes["deuteranopia"].resolve()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if theme.Colours.Complete != "bold blue" {
+		t.Errorf("expected complete colour to be overridden, got %q", theme.Colours.Complete)
+	}
+	if theme.Colours.Failed != Themes["high-contrast"].Colours.Failed {
+		t.Errorf("expected failed colour to be inherited, got %q", theme.Colours.Failed)
+	}
+	if theme.Symbols.InProgress != "~" || theme.Symbols.Failed != "✗" {
+		t.Errorf("unexpected symbols %+v", theme.Symbols)
+	}
+}
+
+func TestParseThemesErrors(t *testing.T) {
+	for name, input := range map[string]string{
+		"no name":        "themes:\n  - colours:\n      failed: red\n",
+		"unknown colour": "themes:\n  - name: bad\n    colours:\n      failed: orange\n",
+		"unknown base":   "themes:\n  - name: bad\n    extends: missing\n",
+	} {
+		_, err := ParseThemes([]byte(input))
+		if err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestSetTheme(t *testing.T) {
+	setColour(t, true)
+	setTheme(t, Themes["ascii"])
+
+	if s := mapStatus("CREATE_COMPLETE").symbol; s != "+" {
+		t.Errorf("expected an ASCII symbol, got %q", s)
+	}
+
+	err := SetTheme(Themes["monochrome"])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := ColouriseStatus("CREATE_COMPLETE"); s != console.Plain("CREATE_COMPLETE") {
+		t.Errorf("expected no colour, got %q", s)
+	}
+	if s := ColouriseStatus("CREATE_FAILED"); s != console.Bold("CREATE_FAILED") {
+		t.Errorf("expected failures to be bold, got %q", s)
+	}
+	if ActiveTheme().Name != "monochrome" {
+		t.Errorf("expected monochrome to be active, got %q", ActiveTheme().Name)
+	}
+}
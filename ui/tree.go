@@ -0,0 +1,209 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/common-fate/cloudform/console"
+	"github.com/common-fate/cloudform/status"
+)
+
+// stackNode is a stack in the live resource tree
+type stackNode struct {
+	name      string
+	status    string
+	summary   string
+	resources []*resourceNode
+	// started is when the stack's current operation started
+	started time.Time
+}
+
+// resourceNode is a single resource in the live resource tree
+type resourceNode struct {
+	logicalID    string
+	physicalID   string
+	resourceType string
+	status       string
+	reason       string
+	// since is when the resource entered its current status
+	since time.Time
+	// action is the change set's action for the resource, such as "create".
+	// It is empty for resources which aren't in the stack's change set.
+	action string
+	// slow is set for resources which have been in progress for longer than their threshold
+	slow bool
+	// nested is set for nested stacks
+	nested *stackNode
+}
+
+// category returns how the resource is displayed while its stack goes through op.
+// Nested stacks are displayed using their own status.
+func (r *resourceNode) category(op status.Operation) statusCategory {
+	if r.nested != nil {
+		return mapStatus(r.nested.status).category
+	}
+	return mapResourceStatus(r.status, op).category
+}
+
+// operation returns the operation the stack is going through
+func (n *stackNode) operation() status.Operation {
+	return status.Stack(n.status).Operation()
+}
+
+// sortRank orders resources so that the most interesting ones come first
+var sortRank = map[statusCategory]int{
+	inProgress: 0,
+	failed:     1,
+	pending:    2,
+	complete:   3,
+}
+
+// sortResources sorts resources which are in progress to the top
+func sortResources(resources []*resourceNode, op status.Operation) {
+	sort.SliceStable(resources, func(i, j int) bool {
+		a := sortRank[resources[i].category(op)]
+		b := sortRank[resources[j].category(op)]
+		if a != b {
+			return a < b
+		}
+		return resources[i].logicalID < resources[j].logicalID
+	})
+}
+
+// maxTreeLines is the number of lines available to the resource tree for a console
+// of the given height, leaving space for messages and the spinner
+func maxTreeLines(height int) int {
+	if height <= 0 {
+		// We don't know the console size so don't fold anything
+		return 0
+	}
+
+	lines := height - 4
+	if lines < 1 {
+		lines = 1
+	}
+	return lines
+}
+
+// fold levels, from least to most compact
+const (
+	foldNone = iota
+	foldComplete
+	foldPending
+	foldTruncate
+)
+
+// renderStackTree renders a stack and its resources. If maxLines is positive,
+// complete then pending resources are collapsed into counts until the tree fits,
+// and finally the tree is truncated. If width is positive, every line is cut to
+// fit it so that each line takes up exactly one row of the console.
+func renderStackTree(node *stackNode, width, maxLines int, now time.Time) string {
+	var lines []string
+
+	for level := foldNone; level < foldTruncate; level++ {
+		lines = treeLines(node, 0, level, width, false, now)
+		if maxLines <= 0 || len(lines) <= maxLines {
+			return strings.Join(lines, "\n")
+		}
+	}
+
+	hidden := len(lines) - maxLines + 1
+	lines = append(lines[:maxLines-1], console.Grey(fmt.Sprintf("  ... %d more", hidden)))
+
+	return strings.Join(lines, "\n")
+}
+
+// treeLines renders a stack as a list of lines. The resources of stacks which
+// have settled are only listed if expand is set.
+func treeLines(node *stackNode, depth, level, width int, expand bool, now time.Time) []string {
+	indent := strings.Repeat("  ", depth)
+
+	header := fmt.Sprintf("%s: %s", console.Yellow(fmt.Sprintf("Stack %s", node.name)), ColouriseStatus(node.status))
+	if node.summary != "" {
+		header += " " + node.summary
+	}
+	if depth > 0 {
+		header = indent + "- " + header
+	}
+
+	lines := []string{console.Truncate(header, width)}
+
+	// Only list the resources of stacks which are changing
+	if !expand && !status.Stack(node.status).IsInProgress() {
+		return lines
+	}
+
+	op := node.operation()
+	folded := map[statusCategory]int{}
+
+	for _, r := range node.resources {
+		if r.nested != nil {
+			lines = append(lines, treeLines(r.nested, depth+1, level, width, expand, now)...)
+			continue
+		}
+
+		rep := mapResourceStatus(r.status, op)
+
+		if (level >= foldComplete && rep.category == complete) || (level >= foldPending && rep.category == pending) {
+			folded[rep.category]++
+			continue
+		}
+
+		lines = append(lines, resourceLine(r, rep, indent+"  ", width, now))
+	}
+
+	for _, folding := range []struct {
+		category statusCategory
+		label    string
+	}{
+		{pending, "pending"},
+		{complete, "complete"},
+	} {
+		rep := newStatusRep(folding.category)
+		if n := folded[rep.category]; n > 0 {
+			word := "resources"
+			if n == 1 {
+				word = "resource"
+			}
+			line := fmt.Sprintf("%s  %s %s", indent, rep.String(), console.Grey(fmt.Sprintf("%d %s %s", n, word, folding.label)))
+			lines = append(lines, console.Truncate(line, width))
+		}
+	}
+
+	return lines
+}
+
+// resourceLine renders a single resource, truncating its status reason to fit the console width
+func resourceLine(r *resourceNode, rep *statusRep, indent string, width int, now time.Time) string {
+	line := fmt.Sprintf("%s%s %s %s", indent, rep.String(), r.resourceType, console.Yellow(r.logicalID))
+
+	if rep.category == inProgress && !r.since.IsZero() {
+		elapsed := now.Sub(r.since).Truncate(time.Second).String()
+		if r.slow {
+			elapsed += " slow"
+			line += " " + console.Yellow(elapsed)
+		} else {
+			line += " " + console.Grey(elapsed)
+		}
+	}
+
+	if r.reason != "" && rep.category != complete {
+		reason := strings.Join(strings.Fields(r.reason), " ")
+
+		if width > 0 {
+			available := width - console.StringWidth(line) - 1
+			if available < 4 {
+				return console.Truncate(line, width)
+			}
+			if console.StringWidth(reason) > available {
+				reason = console.Truncate(reason, available-3) + "..."
+			}
+		}
+
+		line += " " + statusColour(rep.category)(reason)
+	}
+
+	return console.Truncate(line, width)
+}
@@ -0,0 +1,111 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/common-fate/cloudform/status"
+)
+
+func testTree(now time.Time) *stackNode {
+	resources := []*resourceNode{
+		{logicalID: "Bucket", resourceType: "AWS::S3::Bucket", status: "CREATE_COMPLETE"},
+		{logicalID: "Role", resourceType: "AWS::IAM::Role", status: "CREATE_COMPLETE"},
+		{logicalID: "Queue", resourceType: "AWS::SQS::Queue", status: "REVIEW_IN_PROGRESS"},
+		{logicalID: "Function", resourceType: "AWS::Lambda::Function", status: "CREATE_IN_PROGRESS", reason: "Resource creation Initiated", since: now.Add(-90 * time.Second)},
+		{logicalID: "Database", resourceType: "AWS::CloudFormation::Stack", nested: &stackNode{
+			name:   "Database",
+			status: "CREATE_IN_PROGRESS",
+			resources: []*resourceNode{
+				{logicalID: "Table", resourceType: "AWS::DynamoDB::Table", status: "CREATE_IN_PROGRESS", since: now.Add(-5 * time.Second)},
+			},
+		}},
+	}
+	sortResources(resources, status.OperationCreate)
+
+	return &stackNode{name: "app", status: "CREATE_IN_PROGRESS", resources: resources}
+}
+
+func TestRenderStackTree(t *testing.T) {
+	setColour(t, false)
+
+	now := time.Now()
+
+	for _, tc := range []struct {
+		name     string
+		width    int
+		maxLines int
+		expected []string
+	}{
+		{
+			name: "full",
+			expected: []string{
+				"Stack app: CREATE_IN_PROGRESS",
+				"  - Stack Database: CREATE_IN_PROGRESS",
+				"    o AWS::DynamoDB::Table Table 5s",
+				"  o AWS::Lambda::Function Function 1m30s Resource creation Initiated",
+				"  . AWS::SQS::Queue Queue",
+				"  ✓ AWS::S3::Bucket Bucket",
+				"  ✓ AWS::IAM::Role Role",
+			},
+		},
+		{
+			name:     "fold complete",
+			maxLines: 6,
+			expected: []string{
+				"Stack app: CREATE_IN_PROGRESS",
+				"  - Stack Database: CREATE_IN_PROGRESS",
+				"    o AWS::DynamoDB::Table Table 5s",
+				"  o AWS::Lambda::Function Function 1m30s Resource creation Initiated",
+				"  . AWS::SQS::Queue Queue",
+				"  ✓ 2 resources complete",
+			},
+		},
+		{
+			name:     "truncate",
+			maxLines: 3,
+			expected: []string{
+				"Stack app: CREATE_IN_PROGRESS",
+				"  - Stack Database: CREATE_IN_PROGRESS",
+				"  ... 4 more",
+			},
+		},
+		{
+			name:  "narrow",
+			width: 50,
+			expected: []string{
+				"Stack app: CREATE_IN_PROGRESS",
+				"  - Stack Database: CREATE_IN_PROGRESS",
+				"    o AWS::DynamoDB::Table Table 5s",
+				"  o AWS::Lambda::Function Function 1m30s Resour...",
+				"  . AWS::SQS::Queue Queue",
+				"  ✓ AWS::S3::Bucket Bucket",
+				"  ✓ AWS::IAM::Role Role",
+			},
+		},
+		{
+			name:     "very narrow",
+			width:    24,
+			maxLines: 6,
+			expected: []string{
+				"Stack app: CREATE_IN_PRO",
+				"  - Stack Database: CREA",
+				"    o AWS::DynamoDB::Tab",
+				"  o AWS::Lambda::Functio",
+				"  . AWS::SQS::Queue Queu",
+				"  ✓ 2 resources complete",
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := renderStackTree(testTree(now), tc.width, tc.maxLines, now)
+
+			if d := cmp.Diff(strings.Join(tc.expected, "\n"), actual); d != "" {
+				t.Error(d)
+			}
+		})
+	}
+}
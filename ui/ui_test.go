@@ -13,6 +13,8 @@ import (
 )
 
 func TestColouriseStatus(t *testing.T) {
+	setColour(t, true)
+
 	for input, colour := range map[string]func(...interface{}) string{
 		"ROLLBACK_FAILED":       console.Red,
 		"SOMETHING_ELSE_FAILED": console.Red,
@@ -70,12 +72,15 @@ func TestColouriseDiff(t *testing.T) {
 
 func TestStatusIsSettled(t *testing.T) {
 	for input, expected := range map[string]bool{
-		"STACK_COMPLETE":     true,
-		"STACK_FAILED":       true,
-		"SOMETHING_COMPLETE": true,
-		"SOMETHING_FAILED":   true,
-		"COMPLETE_STACK":     false,
-		"FAILED_STACK":       false,
+		"STACK_COMPLETE":                      true,
+		"STACK_FAILED":                        true,
+		"SOMETHING_COMPLETE":                  true,
+		"SOMETHING_FAILED":                    true,
+		"COMPLETE_STACK":                      false,
+		"FAILED_STACK":                        false,
+		"CREATE_IN_PROGRESS":                  false,
+		"REVIEW_IN_PROGRESS":                  true,
+		"UPDATE_COMPLETE_CLEANUP_IN_PROGRESS": true,
 	} {
 		if statusIsSettled(input) != expected {
 			t.Fail()
@@ -101,3 +106,12 @@ lines
 		t.Errorf(d)
 	}
 }
+
+// setColour enables or disables colour for the rest of a test,
+// regardless of the environment the tests run in
+func setColour(t *testing.T, enabled bool) {
+	previous := console.Default.Colour()
+	t.Cleanup(func() { console.Default.SetColour(previous) })
+
+	console.Default.SetColour(enabled)
+}
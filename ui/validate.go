@@ -0,0 +1,48 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/common-fate/cloudform/console"
+	"github.com/common-fate/cloudform/validate"
+)
+
+// RenderFindings renders the results of validating a template. Each finding shows its
+// line number and, if template is the template body, the source line it applies to.
+func RenderFindings(template string, findings []validate.Finding) string {
+	if len(findings) == 0 {
+		return ""
+	}
+
+	source := strings.Split(template, "\n")
+
+	errs := validate.CountErrors(findings)
+
+	out := strings.Builder{}
+	out.WriteString(fmt.Sprintf("%s %s\n", console.Yellow("Template validation:"),
+		console.Grey(fmt.Sprintf("%d errors, %d warnings", errs, len(findings)-errs))))
+
+	for _, f := range findings {
+		severity := console.Yellow(f.Severity.String())
+		if f.Severity == validate.Error {
+			severity = statusColour(failed)(f.Severity.String())
+		}
+
+		location := "  "
+		if f.Line > 0 {
+			location = console.Grey(fmt.Sprintf("  line %d: ", f.Line))
+		}
+
+		out.WriteString(fmt.Sprintf("%s%s %s %s\n", location, severity, f.Message, console.Grey("("+f.Rule+")")))
+
+		if f.Line > 0 && f.Line <= len(source) {
+			if line := strings.TrimSpace(source[f.Line-1]); line != "" {
+				out.WriteString(console.Grey(fmt.Sprintf("    │ %s", line)))
+				out.WriteString("\n")
+			}
+		}
+	}
+
+	return out.String()
+}
@@ -0,0 +1,37 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/common-fate/cloudform/validate"
+)
+
+func TestRenderFindings(t *testing.T) {
+	setColour(t, false)
+
+	template := "Resources:\n  Table:\n    Type: AWS::DynamoDB::Table\n    Properties:\n      TableName: !Ref Missing\n"
+
+	out := RenderFindings(template, []validate.Finding{
+		{Severity: validate.Warning, Rule: validate.RuleDeletionPolicy, Message: "Table (AWS::DynamoDB::Table) has no DeletionPolicy, so its data is deleted with it", Line: 2},
+		{Severity: validate.Error, Rule: validate.RuleUnresolvedRef, Message: "Ref refers to Missing, which is not a parameter or resource", Line: 5},
+		{Severity: validate.Error, Rule: validate.RuleCloudFormation, Message: "Template format error: Unresolved resource dependencies [Missing]"},
+	})
+
+	expected := `Template validation: 2 errors, 1 warnings
+  line 2: warning Table (AWS::DynamoDB::Table) has no DeletionPolicy, so its data is deleted with it (deletion-policy)
+    │ Table:
+  line 5: error Ref refers to Missing, which is not a parameter or resource (unresolved-ref)
+    │ TableName: !Ref Missing
+  error Template format error: Unresolved resource dependencies [Missing] (cloudformation)
+`
+
+	if diff := cmp.Diff(expected, out); diff != "" {
+		t.Error(diff)
+	}
+
+	if RenderFindings(template, nil) != "" {
+		t.Error("expected nothing to be rendered without findings")
+	}
+}
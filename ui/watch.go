@@ -0,0 +1,228 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/smithy-go/ptr"
+	"github.com/common-fate/cloudform/console"
+	"github.com/common-fate/cloudform/status"
+)
+
+// WatchOpts configures WatchStacks
+type WatchOpts struct {
+	// Follow keeps watching the stacks after they settle, rendering
+	// each new operation until the context is cancelled
+	Follow bool
+	// OnSettle is called each time a stack settles. When following, it is only
+	// called for operations which were in progress while being watched.
+	OnSettle func(WatchResult)
+	// PollInterval is the time between refreshes. It defaults to two seconds.
+	PollInterval time.Duration
+	// Settle decides when each stack has settled and whether its operation succeeded.
+	// By default, any operation is waited for and cleanup isn't.
+	Settle status.SettleRules
+}
+
+// WatchResult is the status of a watched stack
+type WatchResult struct {
+	StackName string
+	StackID   string
+	// Status is the last status seen for the stack
+	Status string
+	// Outcome is the outcome of the operation according to WatchOpts.Settle.
+	// It is OutcomeUnsettled if the stack was still changing when watching stopped.
+	Outcome status.Outcome
+	// Messages are the failure messages collected during the most recent operation
+	Messages []string
+	// RollbackTriggered is true if the stack was rolled back because
+	// one of its rollback triggers went into alarm
+	RollbackTriggered bool
+}
+
+type watchedStack struct {
+	id          string
+	result      WatchResult
+	messages    map[string]bool
+	inOperation bool
+	done        bool
+	// progress and slow follow the current operation's resources
+	progress *progressTracker
+	slow     *slowTracker
+}
+
+func (w *watchedStack) collect() []string {
+	messages := make([]string, 0)
+	for message := range w.messages {
+		messages = append(messages, message)
+	}
+	return messages
+}
+
+// WatchStacks renders live progress for one or more stacks until they have all settled.
+// Stacks which have already settled return immediately.
+// If opts.Follow is set, the stacks are watched until ctx is cancelled.
+// The last known status of each stack is returned along with ctx.Err() if ctx is cancelled.
+func (u *UI) WatchStacks(ctx context.Context, stackNames []string, opts WatchOpts) ([]WatchResult, error) {
+	interval := opts.PollInterval
+	if interval == 0 {
+		interval = time.Second * 2
+	}
+
+	stacks := make([]*watchedStack, len(stackNames))
+	for i, name := range stackNames {
+		stacks[i] = &watchedStack{
+			id:       name,
+			result:   WatchResult{StackName: name},
+			messages: make(map[string]bool),
+		}
+	}
+
+	results := func() []WatchResult {
+		out := make([]WatchResult, len(stacks))
+		for i, w := range stacks {
+			out[i] = w.result
+			out[i].Messages = w.collect()
+		}
+		return out
+	}
+
+	// Start the timer
+	u.spinner.StartTimer("")
+
+	out := strings.Builder{}
+	lastOutput := ""
+
+	finish := func() {
+		u.spinner.StopTimer()
+		u.term.ClearLines(u.term.CountLines(lastOutput))
+		lastOutput = ""
+	}
+
+	for {
+		out.Reset()
+
+		settled := make([]WatchResult, 0)
+		remaining := 0
+
+		for _, w := range stacks {
+			if w.done {
+				continue
+			}
+
+			stack, err := u.cfnClient.GetStack(ctx, w.id)
+			if err != nil && ctx.Err() != nil {
+				finish()
+				return results(), ctx.Err()
+			}
+			if err != nil {
+				finish()
+				return results(), Errorf(err, "error watching stack '%s'", w.result.StackName)
+			}
+
+			// Refresh the stack ID so we can deal with deleted stacks ok
+			w.id = ptr.ToString(stack.StackId)
+			w.result.StackID = w.id
+			w.result.Status = string(stack.StackStatus)
+			w.result.Outcome = opts.Settle.Outcome(status.Stack(stack.StackStatus))
+
+			if reason, ok := triggeredRollback(stack); ok {
+				w.result.RollbackTriggered = true
+				w.messages[reason] = true
+			}
+
+			if w.result.Outcome.IsSettled() {
+				if w.inOperation || !opts.Follow {
+					// Collect the failure messages which appeared since the last refresh
+					node, messages := u.buildStackNode(ctx, stack, w.result.StackName)
+					for _, message := range messages {
+						w.messages[message] = true
+					}
+
+					if w.inOperation {
+						// Record the resources which finished since the last refresh
+						w.progress.observe(node, "")
+					}
+
+					res := w.result
+					res.Messages = w.collect()
+					settled = append(settled, res)
+				}
+
+				w.inOperation = false
+
+				// Deleted stacks can't be modified again
+				if !opts.Follow || stack.StackStatus == "DELETE_COMPLETE" {
+					w.done = true
+				}
+
+				continue
+			}
+
+			if !w.inOperation {
+				// A new operation has started
+				w.messages = make(map[string]bool)
+				w.result.RollbackTriggered = false
+				w.inOperation = true
+				w.progress = newProgressTracker(u.durations)
+				w.slow = newSlowTracker(u.slow)
+			}
+
+			remaining++
+
+			output, messages := u.stackOutput(ctx, stack, w.progress, w.slow)
+
+			// Send the output first
+			out.WriteString(output)
+			out.WriteString("\n")
+
+			if len(messages) > 0 {
+				out.WriteString(console.Yellow("Messages:\n"))
+				for _, message := range messages {
+					w.messages[message] = true
+					out.WriteString(fmt.Sprintf("  - %s\n", message))
+				}
+			}
+		}
+
+		outStr := out.String()
+
+		u.spinner.Pause()
+		u.term.ClearLines(u.term.CountLines(lastOutput))
+		lastOutput = ""
+
+		// Report settled stacks above the live output so they stay on screen
+		if opts.OnSettle != nil {
+			for _, res := range settled {
+				opts.OnSettle(res)
+			}
+		}
+
+		if u.term.IsTTY() {
+			u.term.Print(outStr)
+			lastOutput = outStr
+		}
+		u.spinner.Resume()
+
+		// Check to see if we've finished
+		done := true
+		for _, w := range stacks {
+			if !w.done {
+				done = false
+			}
+		}
+		if done {
+			finish()
+			return results(), nil
+		}
+
+		select {
+		case <-ctx.Done():
+			finish()
+			return results(), ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,481 @@
+// Package validate checks CloudFormation templates for mistakes before they are deployed.
+//
+// The checks run locally on the parsed template, so they catch errors such as references
+// to resources which don't exist without the round trip of creating a change set.
+// Each finding records the line of the template it applies to.
+package validate
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aws-cloudformation/rain/cft"
+	"github.com/aws-cloudformation/rain/cft/parse"
+	"gopkg.in/yaml.v3"
+)
+
+// Severity is how serious a finding is
+type Severity int
+
+const (
+	// Warning is a likely mistake which doesn't stop the template from being deployed
+	Warning Severity = iota
+	// Error is a mistake which stops the template from being deployed
+	Error
+)
+
+func (s Severity) String() string {
+	if s == Error {
+		return "error"
+	}
+	return "warning"
+}
+
+// Rules which findings are reported for
+const (
+	RuleUnresolvedRef      = "unresolved-ref"
+	RuleUnusedParameter    = "unused-parameter"
+	RuleUnknownIntrinsic   = "unknown-intrinsic"
+	RuleCircularDependency = "circular-dependency"
+	RuleDeletionPolicy     = "deletion-policy"
+	// RuleCloudFormation is used for errors returned by CloudFormation's ValidateTemplate
+	RuleCloudFormation = "cloudformation"
+)
+
+// Finding is a problem found in a template
+type Finding struct {
+	Severity Severity
+	Rule     string
+	Message  string
+	// Line is the line of the template the finding applies to, starting at 1.
+	// It is zero if the line isn't known.
+	Line int
+}
+
+func (f Finding) String() string {
+	if f.Line == 0 {
+		return fmt.Sprintf("%s: %s (%s)", f.Severity, f.Message, f.Rule)
+	}
+	return fmt.Sprintf("line %d: %s: %s (%s)", f.Line, f.Severity, f.Message, f.Rule)
+}
+
+// HasErrors returns true if any of the findings is an error
+func HasErrors(findings []Finding) bool {
+	return CountErrors(findings) > 0
+}
+
+// CountErrors returns the number of findings which are errors
+func CountErrors(findings []Finding) int {
+	errs := 0
+	for _, f := range findings {
+		if f.Severity == Error {
+			errs++
+		}
+	}
+	return errs
+}
+
+// intrinsics are the functions CloudFormation understands
+var intrinsics = map[string]bool{
+	"Fn::Base64":           true,
+	"Fn::Cidr":             true,
+	"Fn::FindInMap":        true,
+	"Fn::GetAtt":           true,
+	"Fn::GetAZs":           true,
+	"Fn::ImportValue":      true,
+	"Fn::Join":             true,
+	"Fn::Select":           true,
+	"Fn::Split":            true,
+	"Fn::Sub":              true,
+	"Fn::Transform":        true,
+	"Fn::And":              true,
+	"Fn::Equals":           true,
+	"Fn::If":               true,
+	"Fn::Not":              true,
+	"Fn::Or":               true,
+	"Fn::Contains":         true,
+	"Fn::EachMemberEquals": true,
+	"Fn::EachMemberIn":     true,
+	"Fn::RefAll":           true,
+	"Fn::ValueOf":          true,
+	"Fn::ValueOfAll":       true,
+	"Fn::Length":           true,
+	"Fn::ToJsonString":     true,
+}
+
+// pseudoParameters can be referenced without being declared
+var pseudoParameters = map[string]bool{
+	"AWS::AccountId":        true,
+	"AWS::NotificationARNs": true,
+	"AWS::NoValue":          true,
+	"AWS::Partition":        true,
+	"AWS::Region":           true,
+	"AWS::StackId":          true,
+	"AWS::StackName":        true,
+	"AWS::URLSuffix":        true,
+}
+
+// statefulTypes are resource types which hold data that is lost if they are deleted or replaced
+var statefulTypes = map[string]bool{
+	"AWS::DocDB::DBCluster":              true,
+	"AWS::DynamoDB::GlobalTable":         true,
+	"AWS::DynamoDB::Table":               true,
+	"AWS::EC2::Volume":                   true,
+	"AWS::EFS::FileSystem":               true,
+	"AWS::ElastiCache::ReplicationGroup": true,
+	"AWS::Elasticsearch::Domain":         true,
+	"AWS::KMS::Key":                      true,
+	"AWS::Neptune::DBCluster":            true,
+	"AWS::OpenSearchService::Domain":     true,
+	"AWS::RDS::DBCluster":                true,
+	"AWS::RDS::DBInstance":               true,
+	"AWS::Redshift::Cluster":             true,
+	"AWS::S3::Bucket":                    true,
+	"AWS::Cognito::UserPool":             true,
+}
+
+// subVariable matches the variables in an Fn::Sub string, skipping literals written as ${!Literal}
+var subVariable = regexp.MustCompile(`\$\{([^!}][^}]*)\}`)
+
+// String parses a template and checks it. Templates which can't be parsed return an error.
+func String(template string) ([]Finding, error) {
+	t, err := parse.String(template)
+	if err != nil {
+		return nil, err
+	}
+
+	return Template(t), nil
+}
+
+// Template checks a parsed template. Findings are ordered by line.
+//
+// Templates with a Transform, such as AWS::Serverless, are expanded by CloudFormation
+// into resources which aren't in the template, so references and dependencies
+// aren't checked for them.
+func Template(t cft.Template) []Finding {
+	root := t.Node
+	if root != nil && root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		root = root.Content[0]
+	}
+	if root == nil || root.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	c := checker{
+		parameters: sectionEntries(root, "Parameters"),
+		resources:  sectionEntries(root, "Resources"),
+		used:       make(map[string]bool),
+		deps:       make(map[string][]string),
+		transform:  mapValue(root, "Transform") != nil,
+	}
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		section, value := root.Content[i].Value, root.Content[i+1]
+		switch section {
+		case "Parameters":
+			// Parameters can't use intrinsic functions
+		case "Resources":
+			for j := 0; j+1 < len(value.Content); j += 2 {
+				c.current = value.Content[j].Value
+				c.walk(value.Content[j+1], value.Content[j].Line)
+				c.checkDependsOn(value.Content[j+1])
+			}
+			c.current = ""
+		default:
+			c.walk(value, root.Content[i].Line)
+		}
+	}
+
+	c.checkUnusedParameters()
+	c.checkCycles()
+	c.checkDeletionPolicies()
+
+	sort.SliceStable(c.findings, func(i, j int) bool {
+		return c.findings[i].Line < c.findings[j].Line
+	})
+
+	return c.findings
+}
+
+// checker holds the state of checking a template
+type checker struct {
+	parameters map[string]*yaml.Node
+	resources  map[string]*yaml.Node
+	// used are the parameters which are referenced
+	used map[string]bool
+	// deps are the resources each resource refers to
+	deps map[string][]string
+	// current is the resource being walked
+	current   string
+	transform bool
+	findings  []Finding
+}
+
+func (c *checker) add(severity Severity, rule string, line int, format string, args ...interface{}) {
+	c.findings = append(c.findings, Finding{
+		Severity: severity,
+		Rule:     rule,
+		Message:  fmt.Sprintf(format, args...),
+		Line:     line,
+	})
+}
+
+// walk checks every intrinsic function under n. Nodes created by rain for short form
+// functions such as !Ref have no line, so they use the line of their parent.
+func (c *checker) walk(n *yaml.Node, line int) {
+	if n.Line > 0 {
+		line = n.Line
+	}
+
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			keyLine := line
+			if key.Line > 0 {
+				keyLine = key.Line
+			}
+
+			switch {
+			case key.Value == "Ref":
+				c.checkRef(value, keyLine)
+			case key.Value == "Fn::GetAtt":
+				c.checkGetAtt(value, keyLine)
+			case key.Value == "Fn::Sub":
+				c.checkSub(value, keyLine)
+			case strings.HasPrefix(key.Value, "Fn::ForEach::"):
+				// Loops from the AWS::LanguageExtensions transform
+			case strings.HasPrefix(key.Value, "Fn::") && !intrinsics[key.Value]:
+				c.add(Error, RuleUnknownIntrinsic, keyLine, "%s is not a CloudFormation intrinsic function", key.Value)
+			}
+
+			c.walk(value, keyLine)
+		}
+
+	case yaml.SequenceNode:
+		for _, child := range n.Content {
+			c.walk(child, line)
+		}
+	}
+}
+
+// refer records a reference to name and reports it if it doesn't exist.
+// Resources which are referred to are recorded as dependencies of the current resource.
+func (c *checker) refer(name string, line int, resourceOnly bool, function string) {
+	if _, ok := c.resources[name]; ok {
+		if c.current != "" && name != c.current {
+			c.deps[c.current] = append(c.deps[c.current], name)
+		}
+		return
+	}
+
+	if !resourceOnly {
+		if _, ok := c.parameters[name]; ok {
+			c.used[name] = true
+			return
+		}
+		if pseudoParameters[name] {
+			return
+		}
+	}
+
+	if c.transform {
+		return
+	}
+
+	if resourceOnly {
+		c.add(Error, RuleUnresolvedRef, line, "%s refers to %s, which is not a resource", function, name)
+	} else {
+		c.add(Error, RuleUnresolvedRef, line, "%s refers to %s, which is not a parameter or resource", function, name)
+	}
+}
+
+func (c *checker) checkRef(value *yaml.Node, line int) {
+	if value.Kind != yaml.ScalarNode {
+		return
+	}
+	c.refer(value.Value, line, false, "Ref")
+}
+
+func (c *checker) checkGetAtt(value *yaml.Node, line int) {
+	var name string
+
+	switch value.Kind {
+	case yaml.ScalarNode:
+		name, _, _ = strings.Cut(value.Value, ".")
+	case yaml.SequenceNode:
+		if len(value.Content) == 0 || value.Content[0].Kind != yaml.ScalarNode {
+			return
+		}
+		name = value.Content[0].Value
+	default:
+		return
+	}
+
+	c.refer(name, line, true, "Fn::GetAtt")
+}
+
+func (c *checker) checkSub(value *yaml.Node, line int) {
+	str := value
+	vars := make(map[string]bool)
+
+	if value.Kind == yaml.SequenceNode {
+		if len(value.Content) == 0 {
+			return
+		}
+		str = value.Content[0]
+		if len(value.Content) > 1 && value.Content[1].Kind == yaml.MappingNode {
+			for i := 0; i < len(value.Content[1].Content); i += 2 {
+				vars[value.Content[1].Content[i].Value] = true
+			}
+		}
+	}
+
+	if str.Kind != yaml.ScalarNode {
+		return
+	}
+
+	for _, match := range subVariable.FindAllStringSubmatch(str.Value, -1) {
+		name := strings.TrimSpace(match[1])
+		if vars[name] {
+			continue
+		}
+
+		if resource, _, ok := strings.Cut(name, "."); ok && !pseudoParameters[name] {
+			c.refer(resource, line, true, "Fn::Sub")
+			continue
+		}
+
+		c.refer(name, line, false, "Fn::Sub")
+	}
+}
+
+// checkDependsOn records the resources in a resource's DependsOn and reports those which don't exist
+func (c *checker) checkDependsOn(resource *yaml.Node) {
+	dependsOn := mapValue(resource, "DependsOn")
+	if dependsOn == nil {
+		return
+	}
+
+	names := []*yaml.Node{dependsOn}
+	if dependsOn.Kind == yaml.SequenceNode {
+		names = dependsOn.Content
+	}
+
+	for _, n := range names {
+		if n.Kind != yaml.ScalarNode {
+			continue
+		}
+		c.refer(n.Value, n.Line, true, "DependsOn")
+	}
+}
+
+func (c *checker) checkUnusedParameters() {
+	for name, node := range c.parameters {
+		if !c.used[name] {
+			c.add(Warning, RuleUnusedParameter, node.Line, "parameter %s is never used", name)
+		}
+	}
+}
+
+// checkCycles reports each cycle of dependencies between resources once
+func (c *checker) checkCycles() {
+	if c.transform {
+		return
+	}
+
+	names := make([]string, 0, len(c.resources))
+	for name := range c.resources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int)
+	var stack []string
+
+	var visit func(name string)
+	visit = func(name string) {
+		state[name] = visiting
+		stack = append(stack, name)
+
+		for _, dep := range c.deps[name] {
+			switch state[dep] {
+			case unvisited:
+				visit(dep)
+			case visiting:
+				// The cycle is the part of the stack from dep onwards
+				start := 0
+				for i, n := range stack {
+					if n == dep {
+						start = i
+					}
+				}
+				cycle := append(append([]string{}, stack[start:]...), dep)
+				c.add(Error, RuleCircularDependency, c.resources[dep].Line, "circular dependency between resources: %s", strings.Join(cycle, " -> "))
+			}
+		}
+
+		stack = stack[:len(stack)-1]
+		state[name] = visited
+	}
+
+	for _, name := range names {
+		if state[name] == unvisited {
+			visit(name)
+		}
+	}
+}
+
+func (c *checker) checkDeletionPolicies() {
+	for name, node := range c.resources {
+		resourceType := mapValue(node, "Type")
+		if resourceType == nil || !statefulTypes[resourceType.Value] {
+			continue
+		}
+		if mapValue(node, "DeletionPolicy") != nil {
+			continue
+		}
+
+		c.add(Warning, RuleDeletionPolicy, node.Line, "%s (%s) has no DeletionPolicy, so its data is deleted with it", name, resourceType.Value)
+	}
+}
+
+// sectionEntries returns the entries of a top level section by name, keyed to the line of their name
+func sectionEntries(root *yaml.Node, section string) map[string]*yaml.Node {
+	entries := make(map[string]*yaml.Node)
+
+	value := mapValue(root, section)
+	if value == nil || value.Kind != yaml.MappingNode {
+		return entries
+	}
+
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		key, entry := value.Content[i], value.Content[i+1]
+		// Report findings for the entry at the line of its name
+		located := *entry
+		located.Line = key.Line
+		entries[key.Value] = &located
+	}
+
+	return entries
+}
+
+// mapValue returns the value of a key in a mapping node, or nil if it isn't there
+func mapValue(n *yaml.Node, key string) *yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
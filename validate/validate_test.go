@@ -0,0 +1,94 @@
+package validate
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const template = `Parameters:
+  Env:
+    Type: String
+  Unused:
+    Type: String
+Resources:
+  Bucket:
+    Type: AWS::S3::Bucket
+    Properties:
+      BucketName: !Sub "${Env}-${AWS::Region}-${!Literal}"
+  Table:
+    Type: AWS::DynamoDB::Table
+    DeletionPolicy: Retain
+    DependsOn: Bucket
+    Properties:
+      TableName: !Ref Missing
+      Tags:
+        - Key: bucket
+          Value: !GetAtt Buket.Arn
+  Queue:
+    Type: AWS::SQS::Queue
+    Properties:
+      QueueName:
+        Fn::Concat: [a, b]
+  First:
+    Type: AWS::SNS::Topic
+    Properties:
+      TopicName: !GetAtt Second.TopicName
+  Second:
+    Type: AWS::SNS::Topic
+    Properties:
+      TopicName: !Sub "${First.TopicName}-2"
+Outputs:
+  Queue:
+    Value: !Ref Queue
+`
+
+func TestTemplate(t *testing.T) {
+	findings, err := String(template)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Finding{
+		{Warning, RuleUnusedParameter, "parameter Unused is never used", 4},
+		{Warning, RuleDeletionPolicy, "Bucket (AWS::S3::Bucket) has no DeletionPolicy, so its data is deleted with it", 7},
+		{Error, RuleUnresolvedRef, "Ref refers to Missing, which is not a parameter or resource", 16},
+		{Error, RuleUnresolvedRef, "Fn::GetAtt refers to Buket, which is not a resource", 19},
+		{Error, RuleUnknownIntrinsic, "Fn::Concat is not a CloudFormation intrinsic function", 24},
+		{Error, RuleCircularDependency, "circular dependency between resources: First -> Second -> First", 25},
+	}
+
+	if diff := cmp.Diff(expected, findings); diff != "" {
+		t.Error(diff)
+	}
+	if !HasErrors(findings) {
+		t.Error("expected errors")
+	}
+}
+
+func TestTemplateTransform(t *testing.T) {
+	// Resources created by the transform can't be checked
+	findings, err := String(`Transform: AWS::Serverless-2016-10-31
+Resources:
+  Function:
+    Type: AWS::Serverless::Function
+    Properties:
+      Role: !GetAtt FunctionRole.Arn
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != 0 {
+		t.Errorf("expected no findings, got %v", findings)
+	}
+}
+
+func TestTemplateNotObject(t *testing.T) {
+	findings, err := String("https://example.com/template.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != 0 {
+		t.Errorf("expected no findings, got %v", findings)
+	}
+}